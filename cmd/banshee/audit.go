@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// --- Audit trail (-audit) ---
+
+// auditLog records one JSON line per search API request, so an engagement
+// can show what was searched and when. Unlike -log-file it holds nothing
+// else and never reveals keys, only their fingerprints.
+type auditLog struct {
+	mu sync.Mutex
+	f  *os.File
+	bw *bufio.Writer
+}
+
+type auditEntry struct {
+	Time    string `json:"time"`
+	Target  string `json:"target"`
+	Mode    string `json:"mode"`
+	Engine  string `json:"engine"`
+	Query   string `json:"query"`
+	Page    int    `json:"page"`
+	Start   int    `json:"start"`
+	Key     string `json:"key_fingerprint,omitempty"`
+	Status  int    `json:"status"`
+	Results int    `json:"results"`
+	Error   string `json:"error,omitempty"`
+}
+
+var auditRun *auditLog
+
+func openAudit(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{f: f, bw: bufio.NewWriter(f)}, nil
+}
+
+// keyFingerprint identifies a key without revealing it: the first 16 hex
+// digits of its SHA-256.
+func keyFingerprint(k string) string {
+	if k == "" {
+		return ""
+	}
+	h := sha256.Sum256([]byte(k))
+	return hex.EncodeToString(h[:8])
+}
+
+// record appends e, stamped with the current time. A nil log records
+// nothing.
+func (a *auditLog) record(e auditEntry) {
+	if a == nil {
+		return
+	}
+	e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	e.Query = lg.scrub(e.Query)
+	e.Error = lg.scrub(e.Error)
+	b, _ := json.Marshal(e)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.f == nil {
+		return
+	}
+	a.bw.Write(b)
+	a.bw.WriteByte('\n')
+}
+
+func (a *auditLog) close() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.f == nil {
+		return
+	}
+	if err := a.bw.Flush(); err != nil {
+		logErr("[!] cannot write audit file: %v", err)
+	}
+	a.f.Close()
+	a.f = nil
+}
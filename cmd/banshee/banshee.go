@@ -0,0 +1,2927 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+const (
+	defaultUserAgent = banshee.DefaultUserAgent
+	version          = "1.33.7"
+)
+
+// Set at build time:
+//
+//	go build -ldflags "-X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%d)"
+var (
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+// versionString is the version line printed by -version and in summaries.
+func versionString() string {
+	return fmt.Sprintf("banshee %s (commit %s, built %s)", version, commit, buildDate)
+}
+
+// result is a single link together with the term (dictionary word, content
+// string, extension or dork) and the exact query that produced it.
+type result struct {
+	url    string
+	term   string
+	query  string
+	engine string
+	ext    string // extension whose queries found it, in extension mode
+}
+
+// searchReq is one query to send for every page, with the term it was
+// built from.
+type searchReq struct {
+	term  string
+	query string
+}
+
+// jsonResult is the -json output line for a result.
+type jsonResult struct {
+	URL    string `json:"url"`
+	Target string `json:"target,omitempty"`
+	Term   string `json:"term,omitempty"`
+	Ext    string `json:"ext,omitempty"`
+	Query  string `json:"query,omitempty"`
+	Probe  string `json:"probe,omitempty"`
+	Engine string `json:"engine,omitempty"`
+	Ports  []int  `json:"ports,omitempty"`
+}
+
+// Options holds the settings of a run: everything set from flags plus what
+// is derived from them before the first request. Attack functions copy it to
+// vary the target or term, but a shared instance is never modified.
+type Options struct {
+	// Inputs and flags
+	target            string
+	pages             int
+	dork              string
+	exclusions        string
+	contents          string
+	delay             delayRange
+	dictionary        string
+	allOps            bool // -all: allinurl:/allintext: for -w/-c
+	contentsMode      string
+	extension         string
+	outputPath        string
+	fallbackStdout    bool
+	domainsFile       string
+	proxy             string
+	includeSubdomains bool
+	subdomainMode     bool // set when -s used
+	verbose           bool
+	filterExtensions  string
+	noStatic          bool
+	rawURLs           bool
+	relativeHosts     bool
+	resolve           bool
+	resolveWorkers    int
+	dnsServer         string
+	showIPs           bool
+	hostFormat        string
+	hostsIP           string
+	probe             bool
+	probeWorkers      int
+	probeAliveOnly    bool
+	downloadDir       string
+	downloadWorkers   int
+	downloadMaxMB     float64
+	dedupeLoose       bool
+	dedupeCI          bool
+	uniqueHosts       bool
+	hostCounts        bool
+	countOnly         bool
+	labelTerms        bool
+	groupByTerm       bool
+	groupByExt        bool
+	jsonOutput        bool
+	gf                string
+	gfFile            string
+	paramsOnly        bool
+	uniqueParams      bool
+	blacklistPath     string
+	stripParams       bool
+	stripExtra        string
+	concurrency       int
+	workers           int
+	maxLineMB         float64
+	termBatch         int
+	chunk             int
+	minDelay          float64
+	maxDelay          float64
+	delayJitter       float64
+	flushEvery        int
+	fastDedupe        bool
+	domainTimeout     time.Duration
+	skippedFile       string
+	noResultsFile     string
+	noGlobalDedupe    bool
+	abortEmpty        int
+	maxErrors         int
+	errorsFile        string
+	maxRuntime        time.Duration
+	engine            string
+	engines           []string // parsed from engine
+	sourceList        string
+	sources           map[string]bool // parsed from sourceList
+	waybackLimit      int
+	cacheCheckOn      bool
+	cacheDir          string
+	cacheWorkers      int
+	shodan            bool
+	shodanPorts       []int // open ports of the current target, from -shodan
+	silent            bool
+	logFile           string
+	logFormat         string
+	diffBaseline      string
+	diffMissing       string
+	webhookURL        string
+	webhookSecret     string
+	webhookThreshold  int
+	webhookSample     int
+	notifyList        string
+	notifiers         map[string]bool // parsed from notifyList
+	notifyOn          string
+	slackWebhook      string
+	discordWebhook    string
+	telegramToken     string
+	telegramChat      string
+	telegramLines     int
+	vtAPIKey          string
+	permuteFile       string
+	permuteVerify     string
+	permuteMax        int
+	resumeFile        string
+	auditFile         string
+	summaryFile       string
+	manifestFile      string
+	rotate            string
+	noColor           bool
+	debug             bool
+	cx                string
+	keysFile          string
+	modes             string
+	chain             bool
+	recursion         int
+	domainLimit       int
+	skipTargets       int
+	shuffle           bool
+	timestamps        bool
+	keyCooldown       time.Duration
+	keyInterval       time.Duration
+	requestTimeout    time.Duration
+	skipProxyCheck    bool
+	proxyMode         string
+	recursionMaxReqs  int
+
+	// Derived
+	excludeTargets string
+	excludePaths   []string
+	excludeHosts   []string // wildcard -x entries, also dropped client side
+	inFile         string
+	inUrl          []string
+	filterExts     map[string]struct{}
+	gfPatterns     []*regexp.Regexp
+	bl             *blacklist
+	stripSet       map[string]struct{}
+	modeList       []string
+	wildcard       bool // plain searches are site:*.target (-recursion)
+}
+
+// Config is what the attack functions run on: the Options plus the state
+// shared by every run in the process. Copying a Config (c2 := *c) forks the
+// options but keeps sharing the key pool and HTTP client.
+type Config struct {
+	Options
+
+	provider  banshee.Provider
+	client    *http.Client
+	seen      *SafeSet // results already emitted in this -f run; nil when not deduping across targets
+	track     *targetTracker
+	count     *targetCount                // the current target's results under -count
+	providers map[string]banshee.Provider // every selected engine, by name
+	stats     *engineStats
+	pace      *pacer         // shared request pacing in serve mode; nil otherwise
+	rotation  rotatePolicy   // parsed -rotate
+	written   *atomic.Int64  // counts the results written by a serve job; nil otherwise
+	announced bool           // the target header was printed (-modes)
+	flush     func([]result) // takes every -flush-every results of a urlRun as they come
+}
+
+// targetTracker counts, across every dorkRun for one target, the queries
+// that came back empty before the first result, so that a dead target can
+// be abandoned after -abort-empty of them instead of walking every term,
+// and the requests that failed in a row, for -max-errors.
+type targetTracker struct {
+	mu      sync.Mutex
+	empty   int
+	found   bool
+	logged  bool
+	errors  int   // consecutive failed requests
+	lastErr error // the last of them
+	failed  int   // failed requests in total, for -summary
+	pages   int   // result pages fetched, for -summary
+}
+
+func (t *targetTracker) record(found bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if found {
+		t.found = true
+	} else {
+		t.empty++
+	}
+}
+
+// requestDone counts a failed request towards -max-errors, or resets the
+// count after a successful one. Errors that say nothing about the target,
+// like a key running out, are left out.
+func (t *targetTracker) requestDone(err error) {
+	if t == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, banshee.ErrKeyExhausted) || errors.Is(err, banshee.ErrKeyInvalid) || errors.Is(err, banshee.ErrNoKeys) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err == nil {
+		t.errors = 0
+		return
+	}
+	t.errors++
+	t.failed++
+	t.lastErr = err
+}
+
+// pageDone counts a page that came back, with results or empty.
+func (t *targetTracker) pageDone() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.pages++
+	t.mu.Unlock()
+}
+
+// hopeless reports whether the remaining queries for c.target should be
+// skipped, logging the reason once.
+func (c *Config) hopeless() bool {
+	t := c.track
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c.maxErrors > 0 && t.errors >= c.maxErrors {
+		if !t.logged {
+			t.logged = true
+			logErr("[!] %s: %d requests failed in a row, skipping the rest (last error: %v)", c.target, t.errors, t.lastErr)
+		}
+		return true
+	}
+	if c.abortEmpty <= 0 || t.found || t.empty < c.abortEmpty {
+		return false
+	}
+	if !t.logged {
+		t.logged = true
+		logErr("[!] %s: first %d queries returned nothing, skipping the rest", c.target, t.empty)
+	}
+	return true
+}
+
+// erroredOut reports whether c.target was given up under -max-errors, and
+// appends it with its last error to -errors-file if so.
+func (c *Config) erroredOut() bool {
+	t := c.track
+	if t == nil || c.maxErrors <= 0 {
+		return false
+	}
+	t.mu.Lock()
+	n, last := t.errors, t.lastErr
+	t.mu.Unlock()
+	if n < c.maxErrors {
+		return false
+	}
+	lg.event("target_errors", "target", c.target, "errors", n, "error", last.Error())
+	if c.errorsFile != "" {
+		msg := strings.Join(strings.Fields(lg.scrub(last.Error())), " ")
+		c.output().writeUnique([]string{c.target + "\t" + msg}, c.errorsFile, exactKey)
+	}
+	return true
+}
+
+// runState is the mutable state of a single dorkRun invocation.
+type runState struct {
+	store           []result
+	resultsFound    bool
+	requestCounter  int
+	noResultCounter int
+	dynamicDelay    float64
+	rejected        map[string]bool // queries the API refused as invalid
+	sent            bool            // a request went out, so the next one waits
+	hits            map[string]bool // terms with at least one result
+	failed          bool            // the last page ended on errors, or the run was cut short
+	flushed         int             // results handed to c.flush instead of kept in store
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			serveMain(os.Args[2:])
+			return
+		case "update":
+			updateMain(os.Args[2:])
+			return
+		case "init":
+			initMain(os.Args[2:])
+			return
+		case "merge":
+			mergeMain(os.Args[2:])
+			return
+		}
+	}
+	cfg := &Config{stats: &engineStats{}}
+
+	help, showVersion := registerFlags(flag.CommandLine, cfg)
+	if err := applyConfigFile(flag.CommandLine); err != nil {
+		logErr("[!] config file: %v", err)
+		os.Exit(exitFatal)
+	}
+
+	flag.Parse()
+	if *showVersion {
+		fmt.Println(versionString())
+		return
+	}
+	if cfg.manifestFile != "" {
+		manifestRun = newManifest(cfg.manifestFile, flag.CommandLine)
+	}
+	if cfg.extension == "list-bundles" {
+		printBundles()
+		return
+	}
+	if cfg.dictionary == "list" {
+		printWordlists()
+		return
+	}
+	if cfg.silent {
+		infoOut = os.Stderr
+	}
+	initColor(cfg.noColor)
+	var err error
+	if cfg.rotation, err = parseRotate(cfg.rotate); err != nil {
+		logErr("[!] %v", err)
+		os.Exit(exitFatal)
+	}
+	if cfg.rotate != "" && cfg.outputPath == "" {
+		logErr("[!] -rotate needs -o")
+		os.Exit(exitFatal)
+	}
+	if cfg.diffBaseline != "" {
+		if !fileExists(cfg.diffBaseline) {
+			logErr("[!] -diff baseline not found: %s", cfg.diffBaseline)
+			os.Exit(exitFatal)
+		}
+		diffRun = &diffState{out: cfg.output(), baseline: cfg.diffBaseline, missingPath: cfg.diffMissing, outputPath: cfg.outputPath, seen: map[string]bool{}, lines: map[string][]string{}}
+	}
+	if cfg.hostCounts && !cfg.uniqueHosts {
+		logErr("[!] -uh-count needs -uh")
+		os.Exit(exitFatal)
+	}
+	if cfg.uniqueHosts {
+		for _, f := range []struct {
+			on   bool
+			name string
+		}{{cfg.jsonOutput, "-json"}, {cfg.labelTerms, "-label-terms"}, {cfg.groupByTerm, "-group-by-term"}, {cfg.groupByExt, "-group-by-ext"}} {
+			if f.on {
+				logErr("[!] -uh and %s are exclusive", f.name)
+				os.Exit(exitFatal)
+			}
+		}
+		hostsRun = &hostTally{out: cfg.output(), outputPath: cfg.outputPath, counts: cfg.hostCounts, hits: map[string]int{}}
+	}
+	if cfg.countOnly {
+		for _, f := range []struct {
+			on   bool
+			name string
+		}{{cfg.uniqueHosts, "-uh"}, {cfg.jsonOutput, "-json"}, {cfg.labelTerms, "-label-terms"}, {cfg.groupByTerm, "-group-by-term"},
+			{cfg.groupByExt, "-group-by-ext"}, {cfg.probe, "-probe"}, {cfg.cacheCheckOn, "-cache-check"}, {cfg.downloadDir != "", "-download"}} {
+			if f.on {
+				logErr("[!] -count and %s are exclusive", f.name)
+				os.Exit(exitFatal)
+			}
+		}
+	}
+	if cfg.groupByExt && cfg.extension == "" {
+		logErr("[!] -group-by-ext needs -e")
+		os.Exit(exitFatal)
+	}
+	if cfg.resumeFile != "" {
+		if cfg.domainsFile == "" {
+			logErr("[!] -resume needs -f")
+			os.Exit(exitFatal)
+		}
+		var err error
+		if resumeRun, err = openResume(cfg.resumeFile, optionsHash(flag.CommandLine)); err != nil {
+			logErr("[!] cannot open resume file: %v", err)
+			os.Exit(exitFatal)
+		}
+	}
+	if cfg.auditFile != "" {
+		var err error
+		if auditRun, err = openAudit(cfg.auditFile); err != nil {
+			logErr("[!] cannot open audit file: %v", err)
+			os.Exit(exitFatal)
+		}
+	}
+	if cfg.summaryFile != "" {
+		if cfg.domainsFile == "" {
+			logErr("[!] -summary needs -f")
+			os.Exit(exitFatal)
+		}
+		var err error
+		if summaryRun, err = openSummary(cfg.summaryFile); err != nil {
+			logErr("[!] cannot open summary file: %v", err)
+			os.Exit(exitFatal)
+		}
+	}
+	if cfg.logFile != "" {
+		if cfg.logFormat != "text" && cfg.logFormat != "json" {
+			logErr("[!] -log-format must be text or json")
+			os.Exit(exitFatal)
+		}
+		if err := lg.open(cfg.logFile, cfg.logFormat); err != nil {
+			logErr("[!] cannot open log file: %v", err)
+			os.Exit(exitFatal)
+		}
+	}
+	maxLineSize = int(cfg.maxLineMB * 1024 * 1024)
+	engines, err := parseEngines(cfg.engine)
+	if err != nil {
+		logErr("[!] %v", err)
+		os.Exit(exitFatal)
+	}
+	cfg.engines = engines
+	if cfg.sources, err = parseSources(cfg.sourceList); err != nil {
+		logErr("[!] %v", err)
+		os.Exit(exitFatal)
+	}
+	if cfg.notifiers, err = parseNotifiers(cfg.notifyList); err != nil {
+		logErr("[!] %v", err)
+		os.Exit(exitFatal)
+	}
+	lg.addSecrets(cfg.telegramToken)
+	if err := cfg.checkNotifiers(); err != nil {
+		logErr("[!] %v", err)
+		os.Exit(exitFatal)
+	}
+	if cfg.recursion < 0 {
+		logErr("[!] -recursion must be 0 or more")
+		os.Exit(exitFatal)
+	}
+	if cfg.domainLimit < 0 || cfg.skipTargets < 0 {
+		logErr("[!] -dl and -skip must be 0 or more")
+		os.Exit(exitFatal)
+	}
+	if cfg.permuteVerify != "dns" && cfg.permuteVerify != "search" {
+		logErr("[!] -permute-verify must be dns or search")
+		os.Exit(exitFatal)
+	}
+	if cfg.minDelay < 0 || cfg.delayJitter < 0 || (cfg.maxDelay > 0 && cfg.minDelay > cfg.maxDelay) {
+		logErr("[!] Invalid delay bounds: need 0 <= -min-delay <= -max-delay and -delay-jitter >= 0")
+		os.Exit(exitFatal)
+	}
+
+	if *help {
+		showBanner()
+		printUsage()
+		return
+	}
+
+	// Graceful Ctrl+C handling: first signal -> cancel context; second signal -> hard exit
+	root := context.Background()
+	if cfg.maxRuntime > 0 {
+		// behaves like Ctrl+C when it fires, with its own exit code
+		var stop context.CancelFunc
+		root, stop = context.WithTimeout(root, cfg.maxRuntime)
+		defer stop()
+	}
+	ctx, cancel := context.WithCancel(root)
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		count := 0
+		for sig := range sigCh {
+			count++
+			if count == 1 {
+				logErr("[!] Caught %s, attempting graceful shutdown... (press Ctrl+C again to force)", sig.String())
+				cancel()
+			} else {
+				logErr("[!] Force exiting.")
+				os.Exit(exitInterrupted) // hard exit: buffered output is not flushed
+			}
+		}
+	}()
+	defer func() {
+		signal.Stop(sigCh)
+		close(sigCh)
+		cancel()
+		closeOutputs()
+		resumeRun.close()
+		auditRun.close()
+		summaryRun.close()
+		lg.close()
+	}()
+
+	// HTTP client with optional proxy
+	cl, err := buildHTTPClient(cfg.proxy, cfg.proxyMode)
+	if err != nil {
+		logErr("[!] Invalid proxy: %v", err)
+		exit(exitFatal)
+	}
+	if cfg.requestTimeout >= cl.Timeout {
+		// let -request-timeout alone bound each request
+		cl.Timeout = 0
+	}
+	cfg.client = cl
+
+	// Open -o before any request is spent on results that couldn't be saved
+	if cfg.outputPath != "" {
+		if _, err := openSink(cfg.outputPath, cfg.output()); err != nil {
+			if !cfg.fallbackStdout {
+				logErr("[!] cannot open output file: %v (use -fallback-stdout to print results instead)", err)
+				exit(exitFatal)
+			}
+			logErr("[!] cannot open output file: %v; results go to stdout", err)
+		}
+	}
+
+	// Load API keys...
+	if err := cfg.loadProviders(); err != nil {
+		logErr("[!] %v (run `banshee init` to set up keys)", err)
+		exit(exitFatal)
+	}
+	cfg.watchKeyReload()
+	cfg.logProxy()
+	if !cfg.skipProxyCheck {
+		if err := cfg.checkProxy(ctx); err != nil {
+			logErr("[!] %v (use -skip-proxy-check to go ahead anyway)", err)
+			exit(exitFatal)
+		}
+	}
+
+	// Preprocess helpers...
+	if err := cfg.prepare(); err != nil {
+		logErr("[!] %v", err)
+		exit(exitFatal)
+	}
+	if m := cfg.flagModes(); cfg.modeList == nil && len(m) > 1 {
+		how := "run one after another, without deduplicating across them"
+		if cfg.domainsFile != "" {
+			how = "are not combined with -f: only one of them runs"
+		}
+		logErr("[!] WARNING: %s given together %s; use -modes to chain them", strings.Join(m, ", "), how)
+	}
+
+	// Domains file flow
+	if cfg.domainsFile != "" {
+		cfg.runDomainsFile(ctx)
+		return
+	}
+
+	// Single target flow
+	if strings.TrimSpace(cfg.target) == "" {
+		showErrorAndExit()
+	}
+	t, err := banshee.NormalizeTarget(cfg.target)
+	if err != nil {
+		logErr("[!] %v", err)
+		exit(exitFatal)
+	}
+	cfg.target = t
+
+	if cfg.shodan && isIPTarget(cfg.target) {
+		manifestRun.target(cfg.target)
+		cfg.shodanAttack(ctx)
+		if ctx.Err() != nil {
+			cfg.interrupted(ctx)
+		}
+		cfg.finish()
+		return
+	}
+
+	cfg.track = &targetTracker{}
+	if cfg.countOnly {
+		cfg.count = newTargetCount()
+	}
+	lg.event("target_start", "target", cfg.target)
+	manifestRun.target(cfg.target)
+	ran := true
+	if cfg.modeList != nil {
+		cfg.runModes(ctx)
+	} else {
+		ran = cfg.runFlagModes(ctx)
+	}
+	if !ran {
+		showErrorAndExit()
+	}
+	lg.event("target_done", "target", cfg.target, "results", savedResults())
+	if ctx.Err() != nil {
+		cfg.interrupted(ctx)
+	}
+	if !cfg.erroredOut() && !keysRanOut.Load() {
+		cfg.writeCount()
+	}
+	cfg.finish()
+}
+
+// runDomainsFile runs every target of -f and exits.
+func (cfg *Config) runDomainsFile(ctx context.Context) {
+	if err := cfg.readDomainsFile(ctx); err != nil {
+		// Cancelled: everything found so far has been written
+		if ctx.Err() != nil {
+			cfg.interrupted(ctx)
+		}
+		logErr("%v", err)
+		exit(exitFatal)
+	}
+	cfg.finish()
+}
+
+// runFlagModes runs, one after another, every mode whose flag is set, and
+// reports whether there was any.
+func (cfg *Config) runFlagModes(ctx context.Context) bool {
+	var ran bool
+	if cfg.target != "" && cfg.dictionary != "" {
+		ran = true
+		cfg.dictionaryAttack(ctx)
+	}
+	if cfg.target != "" && cfg.extension != "" {
+		ran = true
+		cfg.extensionAttack(ctx)
+	}
+	if cfg.target != "" && cfg.subdomainMode {
+		ran = true
+		cfg.subdomainAttack(ctx)
+	}
+	if cfg.target != "" && cfg.contents != "" {
+		ran = true
+		cfg.contentsAttack(ctx)
+	}
+	if cfg.target != "" && cfg.dork != "" {
+		ran = true
+		emit := func(res []result) { cfg.emit(ctx, res) }
+		if res := cfg.urlRun(ctx, "", emit); len(res) > 0 {
+			emit(res)
+		}
+	}
+	return ran
+}
+
+// registerFlags defines every command-line flag on fs, storing into cfg.
+// The serve command reuses it to parse job options with the same defaults.
+func registerFlags(fs *flag.FlagSet, cfg *Config) (help, showVersion *bool) {
+	help = fs.Bool("h", false, "Display help")
+	fs.BoolVar(help, "help", *help, "Display help")
+	showVersion = fs.Bool("V", false, "Print version and build information")
+	fs.BoolVar(showVersion, "version", false, "Print version and build information")
+
+	fs.StringVar(&cfg.domainsFile, "f", "", "Specify a file containing domains to target")
+	fs.StringVar(&cfg.domainsFile, "file", "", "Specify a file containing domains to target")
+
+	fs.BoolVar(&cfg.subdomainMode, "s", false, "Lists subdomains of the specified domain")
+	fs.BoolVar(&cfg.subdomainMode, "subdomains", false, "Lists subdomains of the specified domain")
+
+	fs.BoolVar(&cfg.includeSubdomains, "a", false, "Aggressive crawling (subdomains included)")
+	fs.BoolVar(&cfg.includeSubdomains, "recursive", false, "Aggressive crawling (subdomains included)")
+
+	fs.IntVar(&cfg.pages, "p", 0, "Specify the number of pages")
+	fs.IntVar(&cfg.pages, "pages", 0, "Specify the number of pages")
+
+	fs.StringVar(&cfg.dork, "q", "", "Specify a query string")
+	fs.StringVar(&cfg.dork, "query", "", "Specify a query string")
+
+	fs.StringVar(&cfg.exclusions, "x", "", "Excludes targets in searches (comma-separated or file)")
+	fs.StringVar(&cfg.exclusions, "exclusions", "", "Excludes targets in searches (comma-separated or file)")
+
+	fs.StringVar(&cfg.contents, "c", "", "Specify relevant content in comma-separated files or file path")
+	fs.StringVar(&cfg.contents, "contents", "", "Specify relevant content in comma-separated files or file path")
+
+	fs.Var(&cfg.delay, "d", "Delay in seconds between requests, or a MIN-MAX range to draw each one from")
+	fs.Var(&cfg.delay, "delay", "Delay in seconds between requests, or a MIN-MAX range to draw each one from")
+
+	fs.StringVar(&cfg.contentsMode, "c-mode", contentsPhrase, "How -c values match: phrase (exact, quoted) or any (any of their words)")
+	fs.BoolVar(&cfg.allOps, "all", false, "Search -w terms with allinurl: and -c values with allintext: (every word, any order)")
+	fs.StringVar(&cfg.dictionary, "w", "", "Specify a DICTIONARY/paths/files (comma-separated or file)")
+	fs.StringVar(&cfg.dictionary, "word", "", "Specify a DICTIONARY/paths/files (comma-separated or file)")
+
+	fs.StringVar(&cfg.extension, "e", "", "Specify comma-separated extensions or file")
+	fs.StringVar(&cfg.extension, "extensions", "", "Specify comma-separated extensions or file")
+
+	fs.StringVar(&cfg.outputPath, "o", "", "Export the results to a file (results only)")
+	fs.BoolVar(&cfg.fallbackStdout, "fallback-stdout", false, "Print results to stdout when -o can't be opened or written instead of failing")
+	fs.StringVar(&cfg.outputPath, "output", "", "Export the results to a file (results only)")
+
+	fs.StringVar(&cfg.target, "u", "", "Specify a DOMAIN or IP Address")
+	fs.StringVar(&cfg.target, "url", "", "Specify a DOMAIN or IP Address")
+
+	fs.StringVar(&cfg.proxy, "r", "", "Specify an [protocol://]host[:port] proxy")
+	fs.StringVar(&cfg.proxy, "proxy", "", "Specify an [protocol://]host[:port] proxy")
+	fs.StringVar(&cfg.proxyMode, "proxy-mode", proxyFlag, "How -proxy and HTTP(S)_PROXY/NO_PROXY combine: flag, env or merged")
+	fs.BoolVar(&cfg.skipProxyCheck, "skip-proxy-check", false, "Don't test the proxy with one request to the API before the run")
+	fs.DurationVar(&cfg.requestTimeout, "request-timeout", 25*time.Second, "Give up on a single API request after this long and retry it (0 leaves it to the 30s client timeout)")
+
+	fs.BoolVar(&cfg.verbose, "v", false, "Enable verbose")
+	fs.BoolVar(&cfg.verbose, "verbose", false, "Enable verbose")
+
+	fs.StringVar(&cfg.filterExtensions, "fe", "", "Drop results with these extensions (comma-separated or file)")
+	fs.StringVar(&cfg.filterExtensions, "filter-extensions", "", "Drop results with these extensions (comma-separated or file)")
+
+	fs.BoolVar(&cfg.noStatic, "no-static", false, "Drop static assets (css, js, images, fonts) from results")
+
+	fs.BoolVar(&cfg.rawURLs, "raw-urls", false, "Output links exactly as returned by the API (no decoding)")
+
+	fs.BoolVar(&cfg.relativeHosts, "relative", false, "Print subdomains relative to the registered domain (e.g. dev.api)")
+
+	fs.BoolVar(&cfg.resolve, "resolve", false, "Drop discovered subdomains that do not resolve (NXDOMAIN)")
+	fs.IntVar(&cfg.resolveWorkers, "resolve-workers", 20, "Number of concurrent DNS lookups for -resolve")
+	fs.StringVar(&cfg.dnsServer, "dns", "", "DNS server used by -resolve, e.g. 1.1.1.1 or 1.1.1.1:53")
+	fs.BoolVar(&cfg.showIPs, "show-ips", false, "Annotate resolved subdomains with their A/AAAA records")
+	fs.StringVar(&cfg.hostFormat, "format", "", "With -s, write massdns (bare hostnames) or hosts (\"IP hostname\" lines, resolves them) instead of the usual output")
+	fs.StringVar(&cfg.hostsIP, "hosts-ip", "4", "Addresses for -format hosts: 4 or 6 (first of that family, else the other) or both (every address)")
+
+	fs.BoolVar(&cfg.probe, "probe", false, "Probe result URLs and annotate them with status code, length and title")
+	fs.IntVar(&cfg.probeWorkers, "probe-workers", 10, "Number of concurrent requests for -probe")
+	fs.BoolVar(&cfg.probeAliveOnly, "probe-alive-only", false, "With -probe, drop URLs that could not be fetched")
+
+	fs.StringVar(&cfg.downloadDir, "download", "", "Download files found in extension mode into this directory")
+	fs.IntVar(&cfg.downloadWorkers, "download-workers", 4, "Number of concurrent downloads for -download")
+	fs.Float64Var(&cfg.downloadMaxMB, "download-max-size", 50, "Skip downloads larger than this many MB (0 = no limit)")
+
+	fs.BoolVar(&cfg.dedupeLoose, "dedupe-loose", false, "Deduplicate ignoring the scheme and a leading www.")
+	fs.BoolVar(&cfg.dedupeCI, "dedupe-ci", false, "Deduplicate ignoring case in URL paths and queries (hosts always are)")
+
+	fs.BoolVar(&cfg.uniqueHosts, "uh", false, "Print each host that had results once instead of the URLs")
+	fs.BoolVar(&cfg.uniqueHosts, "unique-hosts", false, "Print each host that had results once instead of the URLs")
+	fs.BoolVar(&cfg.hostCounts, "uh-count", false, "With -uh, prefix each host with its number of results (count<TAB>host)")
+	fs.BoolVar(&cfg.countOnly, "count", false, "Print target<TAB>number of unique results for each target instead of the results")
+
+	fs.BoolVar(&cfg.labelTerms, "label-terms", false, "Prefix each result with the term that found it (term<TAB>url)")
+	fs.BoolVar(&cfg.groupByTerm, "group-by-term", false, "Group results under a header per term that found them")
+	fs.BoolVar(&cfg.groupByExt, "group-by-ext", false, "In extension mode, group results under a header per extension")
+
+	fs.BoolVar(&cfg.timestamps, "timestamps", false, "Prefix each new output line with its RFC3339 time and a tab")
+	fs.StringVar(&cfg.rotate, "rotate", "", "Start a new -o file, renaming the old one with a timestamp suffix, at size=N[KB|MB|GB] or daily")
+	fs.BoolVar(&cfg.jsonOutput, "json", false, "Write results as JSON lines (url, target, term, query)")
+
+	fs.StringVar(&cfg.gf, "gf", "", "Keep only URLs matching these gf-style patterns (redirect,idor,lfi,ssrf,debug)")
+	fs.StringVar(&cfg.gfFile, "gf-file", "", "JSON file with custom gf-style patterns ({\"name\": [\"regex\", ...]})")
+
+	fs.BoolVar(&cfg.paramsOnly, "params-only", false, "Keep only URLs with query parameters")
+	fs.BoolVar(&cfg.uniqueParams, "unique-params", false, "Deduplicate by host, path and parameter names (?id=1 == ?id=2)")
+
+	fs.StringVar(&cfg.blacklistPath, "bl", "", "File of URLs (exact, prefix* or re:regex) to never output")
+	fs.StringVar(&cfg.blacklistPath, "blacklist", "", "File of URLs (exact, prefix* or re:regex) to never output")
+
+	fs.BoolVar(&cfg.stripParams, "strip-params", false, "Remove tracking parameters (utm_*, gclid, fbclid, ...) from results")
+	fs.StringVar(&cfg.stripExtra, "strip-params-extra", "", "Additional parameter names to remove (comma-separated or file, implies -strip-params)")
+
+	fs.IntVar(&cfg.concurrency, "concurrency", 1, "Number of query variants fetched in parallel per page")
+	fs.IntVar(&cfg.chunk, "chunk", 1, "OR up to N terms of a -c file into one query (1 searches each term on its own)")
+	fs.IntVar(&cfg.workers, "workers", 1, "Number of extensions or content terms searched in parallel")
+
+	fs.Float64Var(&cfg.maxLineMB, "max-line-size", 10, "Maximum length in MB of a single line in input files")
+
+	fs.IntVar(&cfg.termBatch, "term-batch", 100, "Dictionary file terms queried per batch")
+
+	fs.Float64Var(&cfg.minDelay, "min-delay", 0.25, "Lower bound in seconds for the adaptive delay")
+	fs.Float64Var(&cfg.maxDelay, "max-delay", 5, "Upper bound in seconds for the adaptive delay")
+	fs.Float64Var(&cfg.delayJitter, "delay-jitter", 0, "Random extra delay, as a fraction of each sleep")
+
+	fs.IntVar(&cfg.flushEvery, "flush-every", 0, "Write results every N and dedupe by fingerprint to bound memory (0 disables)")
+	fs.BoolVar(&cfg.fastDedupe, "fast-dedupe", false, "Dedupe against -o and across results by 64-bit fingerprint instead of the full lines")
+
+	fs.DurationVar(&cfg.domainTimeout, "domain-timeout", 0, "With -f, give up on a target after this long (e.g. 10m; 0 disables)")
+	fs.StringVar(&cfg.skippedFile, "skipped-file", "", "With -f, append targets that timed out to this file")
+	fs.StringVar(&cfg.noResultsFile, "no-results-file", "", "Append targets (with mode and term) whose searches completed without results to this file")
+	fs.IntVar(&cfg.domainLimit, "dl", 0, "With -f, process at most this many targets")
+	fs.IntVar(&cfg.skipTargets, "skip", 0, "With -f, leave out the first N targets of the file")
+	fs.BoolVar(&cfg.shuffle, "shuffle", false, "With -f, process the targets in random order (with -dl: a random sample)")
+	fs.BoolVar(&cfg.noGlobalDedupe, "no-global-dedupe", false, "With -f, dedupe results per target instead of across the whole run")
+	fs.IntVar(&cfg.maxErrors, "max-errors", 0, "Skip the rest of a target after N requests for it failed in a row (0 disables)")
+	fs.StringVar(&cfg.errorsFile, "errors-file", "", "Append targets skipped by -max-errors to this file, with their last error")
+	fs.IntVar(&cfg.abortEmpty, "abort-empty", 0, "Skip a target's remaining queries when its first N all return nothing")
+	fs.DurationVar(&cfg.maxRuntime, "max-runtime", 0, "Stop the whole run gracefully after this long (e.g. 45m; 0 disables)")
+	fs.StringVar(&cfg.modes, "modes", "", "Run these modes in order: subs, files, dirs, contents, dork (comma-separated)")
+	fs.BoolVar(&cfg.chain, "chain", false, "With -modes, also run the modes after subs on every subdomain found")
+	fs.IntVar(&cfg.recursion, "recursion", 0, "With -s, search under found subdomains this many levels deeper")
+	fs.IntVar(&cfg.recursionMaxReqs, "recursion-max-requests", 200, "Most API requests spent on -recursion per target (0 = no limit)")
+	fs.DurationVar(&cfg.keyInterval, "key-interval", 0, "Least time between two requests with the same API key; ready keys are preferred (e.g. 1s)")
+	fs.DurationVar(&cfg.keyCooldown, "key-cooldown", 0, "How long an exhausted API key rests before it is tried again (default: until the daily quota reset, midnight Pacific Time)")
+	fs.StringVar(&cfg.keysFile, "k", "", "API key file for the (first) engine (default $BANSHEE_KEYS, then keys.txt in the config directory)")
+	fs.StringVar(&cfg.keysFile, "keys", "", "API key file for the (first) engine (default $BANSHEE_KEYS, then keys.txt in the config directory)")
+	fs.StringVar(&cfg.cx, "cx", "", "Google Programmable Search Engine ID (default: banshee's own)")
+	fs.StringVar(&cfg.engine, "engine", "google", "Search backend(s): google, serpapi, yandex; comma-separated to merge several")
+	fs.StringVar(&cfg.sourceList, "sources", "", "Extra sources to merge in: crtsh, wayback, vt")
+	fs.StringVar(&cfg.vtAPIKey, "vt-key", "", "VirusTotal API key for -sources vt (default $VT_API_KEY or vt-keys.txt)")
+	fs.IntVar(&cfg.waybackLimit, "wayback-limit", 50000, "Most archived URLs fetched per target with -sources wayback (0 = no limit)")
+
+	fs.BoolVar(&cfg.cacheCheckOn, "cache-check", false, "Check whether Google has a cached copy of each result (only dead ones with -probe)")
+	fs.StringVar(&cfg.cacheDir, "cache-dir", "", "With -cache-check, save cached pages to this directory")
+	fs.IntVar(&cfg.cacheWorkers, "cache-workers", 5, "Number of concurrent cache lookups")
+	fs.BoolVar(&cfg.silent, "silent", false, "Only results on stdout; informational output goes to stderr")
+	fs.BoolVar(&cfg.noColor, "no-color", false, "Don't color diagnostics on stderr (also $NO_COLOR)")
+	fs.BoolVar(&cfg.debug, "debug", false, "Print the full body of unreadable API responses")
+	fs.StringVar(&cfg.logFile, "log-file", "", "Append a log of queries, keys, errors and targets to this file")
+	fs.StringVar(&cfg.logFormat, "log-format", "text", "Format of -log-file: text or json")
+	fs.StringVar(&cfg.diffBaseline, "diff", "", "Compare results with this baseline file and output only new ones")
+	fs.StringVar(&cfg.diffMissing, "diff-missing", "", "With -diff, write baseline entries not found again to this file")
+
+	fs.StringVar(&cfg.webhookURL, "webhook", "", "POST a JSON summary here when a run finds new results")
+	fs.StringVar(&cfg.webhookSecret, "webhook-secret", "", "Sign webhook payloads with HMAC-SHA256 using this secret")
+	fs.IntVar(&cfg.webhookThreshold, "webhook-threshold", 1, "Only call the webhook for at least this many new results")
+	fs.IntVar(&cfg.webhookSample, "webhook-sample", 10, "Number of new URLs included in the webhook payload")
+	fs.StringVar(&cfg.notifyList, "notify", "", "Comma-separated chat notifications to send: slack, discord, telegram")
+	fs.StringVar(&cfg.notifyOn, "notify-on", "new", "When to notify: new, always or errors")
+	fs.StringVar(&cfg.slackWebhook, "slack-webhook", os.Getenv("SLACK_WEBHOOK_URL"), "Slack incoming webhook URL (default $SLACK_WEBHOOK_URL)")
+	fs.StringVar(&cfg.discordWebhook, "discord-webhook", os.Getenv("DISCORD_WEBHOOK_URL"), "Discord webhook URL (default $DISCORD_WEBHOOK_URL)")
+	fs.StringVar(&cfg.telegramToken, "telegram-token", os.Getenv("TELEGRAM_BOT_TOKEN"), "Telegram bot token (default $TELEGRAM_BOT_TOKEN)")
+	fs.StringVar(&cfg.telegramChat, "telegram-chat", os.Getenv("TELEGRAM_CHAT_ID"), "Telegram chat ID (default $TELEGRAM_CHAT_ID)")
+	fs.IntVar(&cfg.telegramLines, "telegram-lines", 20, "Number of new URLs included in Telegram messages")
+	fs.BoolVar(&cfg.shodan, "shodan", false, "For IP/CIDR targets, look up hostnames and ports on Shodan and search the hostnames too")
+
+	fs.StringVar(&cfg.permuteFile, "permute", "", "With -s, wordlist for permuting found subdomain labels")
+	fs.StringVar(&cfg.permuteVerify, "permute-verify", "dns", "How to check permutations: dns or search")
+	fs.IntVar(&cfg.permuteMax, "permute-max", 500, "Most permutation candidates checked per target (0 = no limit)")
+	fs.StringVar(&cfg.resumeFile, "resume", "", "With -f, record completed targets in this file and skip them when rerun")
+	fs.StringVar(&cfg.auditFile, "audit", "", "Append a JSON line for every search API request to this file")
+	fs.StringVar(&cfg.summaryFile, "summary", "", "With -f, append a CSV row per target (results, requests, pages, errors, duration, status) to this file")
+	fs.StringVar(&cfg.manifestFile, "manifest", "", "Write a JSON manifest of the run (version, configuration, targets, requests per key, outputs) to this file when it ends")
+	return help, showVersion
+}
+
+// prepare fills in the derived Options from the flag values.
+func (cfg *Config) prepare() error {
+	if cfg.exclusions != "" {
+		cfg.excludeTargets = cfg.buildExclusions(cfg.exclusions, cfg.includeSubdomains)
+		var hosts []string
+		hosts, cfg.excludePaths = cfg.splitExclusions(cfg.exclusions)
+		cfg.excludeHosts = banshee.WildcardHosts(hosts)
+	}
+	switch cfg.contentsMode {
+	case contentsPhrase:
+	case contentsAny:
+		if cfg.allOps {
+			return errors.New("-c-mode any and -all are exclusive")
+		}
+	default:
+		return fmt.Errorf("unknown -c-mode %q (want phrase or any)", cfg.contentsMode)
+	}
+	if cfg.allOps {
+		if err := cfg.checkAllOps(); err != nil {
+			return err
+		}
+	}
+	if cfg.extension != "" && !fileExists(cfg.extension) {
+		exts, err := expandExtensions(cfg.extension)
+		if err != nil {
+			return err
+		}
+		cfg.extension = exts
+	}
+	if cfg.contents != "" {
+		cfg.inFile = cfg.buildContentsQuery()
+	}
+	if wordlistRef(cfg.dictionary) {
+		// fail on an unknown @wordlist now rather than per target
+		r, err := openTerms(cfg.dictionary)
+		if err != nil {
+			return err
+		}
+		r.Close()
+	}
+	if cfg.dictionary != "" && !isTermSource(cfg.dictionary) {
+		// dictionary files and wordlists are streamed in batches by
+		// dictionaryAttack
+		cfg.inUrl = cfg.buildInurlQuery(cfg.dictionary)
+	}
+	if cfg.filterExtensions != "" || cfg.noStatic {
+		cfg.filterExts = buildExtensionFilter(cfg.filterExtensions, cfg.noStatic)
+	}
+	if cfg.gf != "" || cfg.gfFile != "" {
+		pats, err := loadGFPatterns(cfg.gf, cfg.gfFile)
+		if err != nil {
+			return err
+		}
+		cfg.gfPatterns = pats
+	}
+	if cfg.stripParams || cfg.stripExtra != "" {
+		cfg.stripSet = buildStripSet(cfg.stripExtra)
+	}
+	if cfg.blacklistPath != "" {
+		bl, err := loadBlacklist(cfg.blacklistPath)
+		if err != nil {
+			return fmt.Errorf("cannot load blacklist: %w", err)
+		}
+		cfg.bl = bl
+	}
+	if cfg.modes != "" {
+		modes, err := parseModes(cfg.modes)
+		if err != nil {
+			return err
+		}
+		cfg.modeList = modes
+		if err := cfg.checkModes(); err != nil {
+			return err
+		}
+	} else if cfg.chain {
+		return errors.New("-chain needs -modes")
+	}
+	return cfg.checkHostFormat()
+}
+
+// finish ends a completed run: 0 when something was found, 2 when nothing
+// was, 3 when it stopped because every API key ran out.
+func (c *Config) finish() {
+	flushHosts()
+	flushDiff(true)
+	c.notify(true)
+	c.engineSummary()
+	c.writeManifest(false)
+	switch {
+	case keysRanOut.Load() && c.quotaState().Usable == 0:
+		exit(exitKeysExhausted)
+	case found.Load() == 0, c.countOnly && counted.Load() == 0:
+		exit(exitNoResults)
+	}
+	exit(exitOK)
+}
+
+// interrupted reports how much was written before a graceful shutdown and
+// exits with 130, or 124 when -max-runtime ran out. Every mode emits its
+// partial results before returning, so by the time this runs there is
+// nothing left to collect.
+func (c *Config) interrupted(ctx context.Context) {
+	flushHosts()
+	flushDiff(false)
+	c.notify(false)
+	c.engineSummary()
+	c.writeManifest(true)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		q := c.quotaState()
+		logErr("[!] -max-runtime %s reached: %d results saved, %d API requests made, %d/%d keys not exhausted",
+			c.maxRuntime, savedResults(), q.Requests, q.Usable, q.Keys)
+		exit(exitTimeout)
+	}
+	logErr("[!] interrupted: %d results saved", savedResults())
+	exit(exitInterrupted)
+}
+
+func showBanner() {
+	// ASCII banner;
+	fmt.Println("⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⡄⠀⠀⠀⠀⠀")
+	fmt.Println("⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⣀⣇⡀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀")
+	fmt.Println("⠀⠀⠀⠀⠀⠀⢀⣠⠴⠚⠋⠉⢹⣯⠉⠉⠛⠲⢤⣀⠀⠀⠀⠀⠀⠀")
+	fmt.Println("⠀⠀⠀⠀⢀⡴⠋⠀⣣⠤⠒⠒⣺⣿⡒⠒⠢⢤⡃⠉⠳⣄⠀⠀⠀⠀")
+	fmt.Println("⢀⡀⠀⣠⠋⠀⡰⠊⠀⠀⢀⣼⠏⠈⢿⣄⠀⠀⠈⠲⡀⠈⢧⡀⠀⣀")
+	fmt.Println("⠀⠈⢳⠧⢤⣞⠀⠀⠀⢀⣾⠏⠀⠀⠈⢿⣆⠀⠀⠀⢘⣦⠤⢷⠋⠀")
+	fmt.Println("⠀⠀⡾⠤⡼⠈⠛⢦⣤⣾⡏⣠⠶⠲⢦⡈⣿⣦⣤⠞⠋⢹⡤⠼⡇⠀")
+	fmt.Println("⠀⠀⡇⠀⠆⠀⠀⢾⣿⣿⢸⡁⣾⣿⠆⣻⢸⣿⢾⠄⠀⠀⠆⠀⡇⠀")
+	fmt.Println("⠀⠀⣧⠐⢲⠀⣠⡼⠟⣿⡆⠳⢬⣥⠴⢃⣿⡟⠻⣤⡀⢸⠒⢠⡇⠀")
+	fmt.Println("⠀⢀⣸⡤⠞⢏⠁⠀⠀⠘⢿⡄⠀⠀⠀⣼⠟⠀⠀⠀⢙⠟⠦⣼⣀⠀")
+	fmt.Println("⠐⠉⠀⠹⡄⠈⠣⡀⠀⠀⠈⢿⣄⢀⣾⠏⠀⠀⠀⡠⠋⢀⡼⠁⠈⠑")
+	fmt.Println("⠀⠀⠀⠀⠙⢦⣀⠈⡗⠢⠤⢈⣻⣿⣃⠠⠤⠲⡍⢀⣠⠞⠀⠀⠀⠀")
+	fmt.Println("⠀⠀⠀⠀⠀⠀⠈⠛⠦⣄⣀⡀⢸⣏⠀⣀⣀⡤⠞⠋⠀⠀⠀⠀⠀⠀")
+	fmt.Println("⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠉⠉⠉⡏⠉⠉⠁⠀⠀⠀⠀⠀⠀⠀⠀⠀")
+	fmt.Println("⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠇⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀⠀")
+	fmt.Printf(" Banshee v%s\n - Made by Vulnpire\n\n", version)
+}
+
+func printUsage() {
+	fmt.Println(`Usage:
+    -h|--help                                Display this help message.
+    -V|--version                 Print version, commit and build date.
+    -a|--recursive                 Aggressive crawling (subdomains included).
+    -w|--word <DICTIONARY>        Specify a DICTIONARY, PATHS or FILES.
+                     @admin-panels, @api-endpoints, ... are built in; -w list lists them.
+    -e|--extensions <EXTENSION>           Specify comma-separated extensions.
+                     @backups, @configs, ... expand to bundles; -e list-bundles lists them.
+    -u|--url <TARGET>                  Specify a DOMAIN or IP Address.
+    -p|--pages <PAGES>                      Specify the number of PAGES.
+    -x|--exclusions <EXCLUSIONS>  EXCLUDES targets or /paths/ in searches.
+    -d|--delay <DELAY>                Delay in seconds between requests (or MIN-MAX).
+    -s|--subdomains                 Lists subdomains of the specified domain.
+    -c|--contents <TEXT> Specify relevant content in comma-separated files.
+    -c-mode <MODE>   -c matching: phrase (default) or any word.
+    -all             Use allinurl:/allintext: for -w/-c (every word, any order).
+    -o|--output <FILENAME>   Export the results to a file (results only).
+    -fallback-stdout Print results when -o can't be written instead of failing.
+    -r|--proxy <PROXY>        Specify an [protocol://]host[:port] proxy.
+    -proxy-mode <MODE>        flag (default), env or merged; see README.
+    -skip-proxy-check         Don't test the proxy before the run.
+    -request-timeout <DURATION>  Per-request timeout before a retry (default: 25s).
+    -f|--file <FILENAME>   Specify a file containing domains to target.
+    -q|--query <QUERY>     Specify a query string.
+    -v|--verbose      Enable verbose.
+    -fe|--filter-extensions <EXT>   Drop results with these extensions.
+    -no-static       Drop static assets (css, js, images, fonts).
+    -raw-urls        Output links exactly as returned (no decoding).
+    -relative        Print subdomains relative to the apex (with -s).
+    -resolve         Drop subdomains that do not resolve (with -s).
+    -resolve-workers <N>     Concurrent DNS lookups (default 20).
+    -dns <SERVER>    DNS server for -resolve, e.g. 1.1.1.1.
+    -show-ips        Annotate resolved subdomains with A/AAAA records.
+    -format <massdns|hosts>  Write -s results as bare hostnames or "IP hostname".
+    -hosts-ip <4|6|both>     Addresses for -format hosts (default 4).
+    -probe           Annotate results with status code, length and title.
+    -probe-workers <N>       Concurrent requests for -probe (default 10).
+    -probe-alive-only        With -probe, drop URLs that failed to load.
+    -download <DIR>  Download files found in extension mode into DIR.
+    -download-workers <N>    Concurrent downloads (default 4).
+    -download-max-size <MB>  Skip files larger than MB (default 50).
+    -dedupe-loose    Deduplicate ignoring scheme and leading www.
+    -dedupe-ci       Deduplicate ignoring case in paths (first variant kept).
+    -uh, -unique-hosts  Print the hosts that had results instead of the URLs.
+    -uh-count        With -uh, print count<TAB>host.
+    -count           Print target<TAB>count per target instead of results.
+    -label-terms     Print results as term<TAB>url.
+    -group-by-term   Group results under a "# term" header.
+    -group-by-ext    Group extension mode results under a "# ext" header.
+    -json            Write results as JSON lines with term and query.
+    -timestamps      Prefix new output lines with an RFC3339 time and a tab.
+    -rotate <size=N|daily>  Rotate the -o file by size or by day.
+    -gf <PATTERNS>   Keep only URLs matching redirect,idor,lfi,ssrf,debug.
+    -gf-file <FILE>  JSON file with custom gf-style patterns.
+    -params-only     Keep only URLs with query parameters.
+    -unique-params   Deduplicate by host, path and parameter names.
+    -bl|--blacklist <FILE>   Never output URLs matching FILE entries.
+    -strip-params    Remove tracking parameters (utm_*, gclid, ...).
+    -strip-params-extra <NAMES>  More parameter names to remove.
+    -concurrency <N> Query variants fetched in parallel (default 1).
+    -workers <N>     Extensions/content terms in parallel (default 1).
+    -chunk <N>       OR up to N terms of a -c file into one query (default 1).
+    -max-line-size <MB>      Longest accepted input line (default 10).
+    -term-batch <N>  Dictionary file terms per batch (default 100).
+    -min-delay <SEC> Lower bound for the adaptive delay (default 0.25).
+    -max-delay <SEC> Upper bound for the adaptive delay (default 5).
+    -delay-jitter <F>        Random extra delay fraction (off by default).
+    -flush-every <N> Write every N results, bounded-memory dedupe.
+    -fast-dedupe     Fingerprint-based dedupe for very large -o files.
+    -domain-timeout <D>      Per-target time limit with -f (e.g. 10m).
+    -skipped-file <FILE>     Record timed-out targets from -f.
+    -no-results-file <FILE>  Record searches that completed with no results.
+    -no-global-dedupe        With -f, dedupe per target only.
+    -dl <N>                  With -f, process at most N targets.
+    -skip <N>                With -f, leave out the first N targets.
+    -shuffle                 With -f, random target order (random sample with -dl).
+    -abort-empty <N> Give up on a target after N empty queries (off by default).
+    -max-errors <N>  Give up on a target after N failed requests in a row.
+    -errors-file <FILE>      Record targets given up by -max-errors.
+    -max-runtime <D> Wall-clock budget for the run (e.g. 45m).
+    -engine <NAMES>  Search backend(s): google (default), serpapi, yandex.
+    -k|--keys <FILE> API key file (default $BANSHEE_KEYS, then keys.txt).
+    -cx <ID>         Google Programmable Search Engine ID.
+    -key-interval <DURATION>  Least time between requests with one key (ready keys preferred).
+    -key-cooldown <DURATION>  Rest for exhausted keys (default: until midnight PT).
+    -modes <LIST>    Run modes in order: subs,files,dirs,contents,dork.
+    -chain           With -modes, feed subdomains found by subs to later modes.
+    -recursion <N>   With -s, search under found subdomains N levels deeper.
+    -recursion-max-requests <N>  Request cap for -recursion per target (default 200).
+                     A comma-separated list merges several engines.
+    -sources <LIST>  Extra sources: crtsh, vt (with -s), wayback.
+    -vt-key <KEY>    VirusTotal API key for -sources vt.
+    -wayback-limit <N>       Archived URLs per target (default 50000).
+    -cache-check     Report Google cache copies (dead results with -probe).
+    -cache-dir <DIR> Save cached pages found by -cache-check.
+    -cache-workers <N>       Concurrent cache lookups (default 5).
+    -silent          Results only on stdout; messages go to stderr.
+    -no-color        Plain stderr diagnostics (also $NO_COLOR).
+    -debug           Print the full body of unreadable API responses.
+    -log-file <FILE> Log queries, key use, errors and targets to FILE.
+    -log-format <text|json>          Format of -log-file (default text).
+    -diff <FILE>     Output only results not in baseline FILE.
+    -diff-missing <FILE>     With -diff, save baseline entries not seen.
+    -webhook <URL>   POST a JSON summary when new results are found.
+    -webhook-secret <S>      HMAC-SHA256 signing secret for -webhook.
+    -webhook-threshold <N>   Minimum new results to notify (default 1).
+    -webhook-sample <N>      New URLs included in the payload (default 10).
+    -notify <LIST>   Chat notifications to send: slack, discord, telegram.
+    -notify-on <WHEN>        new (default), always or errors.
+    -slack-webhook <URL>     Slack webhook (default $SLACK_WEBHOOK_URL).
+    -discord-webhook <URL>   Discord webhook (default $DISCORD_WEBHOOK_URL).
+    -telegram-token <TOKEN>  Telegram bot token (default $TELEGRAM_BOT_TOKEN).
+    -telegram-chat <ID>      Telegram chat ID (default $TELEGRAM_CHAT_ID).
+    -telegram-lines <N>      New URLs included in Telegram messages (default 20).
+    -shodan          Enrich IP/CIDR targets with Shodan hostnames and ports.
+    -permute <FILE>  With -s, try permutations of found subdomain labels.
+    -permute-verify <dns|search>     How permutations are checked (default dns).
+    -permute-max <N> Most permutations checked per target (default 500).
+    -resume <FILE>   With -f, record completed targets and skip them when rerun.
+    -audit <FILE>    Append a JSON line for every search API request.
+    -summary <FILE>  With -f, append a CSV row of counts per target to FILE.
+    -manifest <FILE> Write a JSON description of the run to FILE at the end.
+
+Examples:
+    banshee -u example.com -e pdf,doc,bak
+    banshee -u example.com -e pdf -p 2
+    banshee -u example.com -e extensionslist.txt -a
+    banshee -u example.com -w config.php,admin,/images/
+    banshee -u example.com -w wp-admin -p 1
+    banshee -u example.com -w wordlist.txt
+    banshee -u example.com -w login.html,search,redirect,?id= -x admin.example.com
+    banshee -u example.com -w admin.html,search,redirect,?id= -x exclusion_list.txt
+    banshee -u example.com -e pdf -x /blog/,/press/,docs.example.com
+    banshee -u example.com -s -p 10 -d 5 -o banshee-subdomains.txt
+    banshee -u example.com -s -resolve -dns 1.1.1.1 -show-ips
+    banshee -u example.com -w login,admin -probe -probe-alive-only
+    banshee -u example.com -e pdf,docx,xlsx -download loot/
+    banshee -u example.com -e extensionslist.txt -workers 4
+    banshee -u example.com -w wp-admin,backup -label-terms
+    banshee -u example.com -q 'inurl:"?"' -a -gf redirect,lfi
+    banshee -u example.com -c Passport,Password,Confidential,Secret
+    banshee -u example.com -r http://proxy.example.com:8080
+    banshee -u example.com -q <query> -a
+    banshee -f domains.txt -w wordlist.txt
+    banshee -u example.com -q 'inurl:login' -no-static -fe txt`)
+}
+
+func showErrorAndExit() {
+	logErr("[!] Error, missing or invalid argument.")
+	printUsage()
+	os.Exit(exitFatal)
+}
+
+// infoOut receives informational messages; -silent moves them from stdout
+// to stderr so that stdout carries results only.
+var infoOut io.Writer = os.Stdout
+
+func logv(v bool, f string, a ...any) {
+	if v {
+		msg := fmt.Sprintf(f, a...)
+		consoleWrite(infoOut, msg)
+		lg.event("info", "msg", msg)
+	}
+}
+
+// logDebug prints -debug diagnostics to stderr when on. Unlike logErr
+// they don't count as errors.
+func logDebug(on bool, f string, a ...any) {
+	if on {
+		msg := fmt.Sprintf(f, a...)
+		consoleWrite(os.Stderr, msg)
+		lg.event("debug", "msg", msg)
+	}
+}
+
+// logInfo prints a status line to stderr, apart from the results on
+// stdout. Unlike logErr it doesn't count as an error.
+func logInfo(f string, a ...any) {
+	msg := fmt.Sprintf(f, a...)
+	consoleWrite(os.Stderr, msg)
+	lg.event("info", "msg", msg)
+}
+
+// errorsLogged counts logErr calls, for -notify-on errors.
+var errorsLogged atomic.Int64
+
+func logErr(f string, a ...any) {
+	errorsLogged.Add(1)
+	msg := fmt.Sprintf(f, a...)
+	consoleWrite(os.Stderr, msg)
+	lg.event("error", "msg", msg)
+}
+
+// --- API Keys ---
+
+// loadAPIKeysDefault reads the keys of a secondary service (shodan, vt)
+// from <service>-keys.txt in the config directories.
+func loadAPIKeysDefault(engine string) ([]string, error) {
+	keys, _, err := loadAPIKeys(engine, "", false)
+	return keys, err
+}
+
+func readApiKeysFromFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var keys []string
+	sc := newLineScanner(f)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	if err := scanErr(sc, path, n); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no API keys in file")
+	}
+	return keys, nil
+}
+
+// --- Query builders ---
+
+func (c *Config) buildExclusions(exclusions string, multiline bool) string {
+	// -site:<ex1> -site:<ex2>… for hosts, followed by -inurl:"..." for paths
+	return banshee.ExclusionQuery(c.splitExclusions(exclusions))
+}
+
+// splitExclusions parses -x into host entries and path entries (those
+// starting with "/").
+func (c *Config) splitExclusions(exclusions string) (hosts, paths []string) {
+	var parts []string
+	if fileExists(exclusions) {
+		parts = c.readTermFile(exclusions)
+	} else {
+		parts = strings.Split(exclusions, ",")
+	}
+	return banshee.SplitExclusions(parts)
+}
+
+// -c-mode values.
+const (
+	contentsPhrase = "phrase" // intext:"internal use only"
+	contentsAny    = "any"    // (intext:"internal" OR intext:"use" OR intext:"only")
+)
+
+func (c *Config) buildContentsQuery() string {
+	// intext:"a" OR intext:"b" for a comma-separated list. A file is
+	// searched line by line by contentsAttack, so only its first line is
+	// used here.
+	if fileExists(c.contents) {
+		lines := c.readTermFile(c.contents)
+		if len(lines) > 0 {
+			return c.contentQuery(lines[0])
+		}
+		return ""
+	}
+	if c.allOps {
+		// a single value, see checkAllOps
+		return c.contentQuery(c.contents)
+	}
+	var parts []string
+	for _, s := range strings.Split(c.contents, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return c.contentsOr(parts)
+}
+
+// contentQuery is the query part searching for one -c value: intext:"v",
+// its words ORed with -c-mode any, or allintext: v with -all.
+func (c *Config) contentQuery(v string) string {
+	switch {
+	case c.allOps:
+		return banshee.AllQuery("allintext", v)
+	case c.contentsMode == contentsAny:
+		return banshee.AnyWordQuery(v)
+	}
+	return banshee.ContentsQuery([]string{v})
+}
+
+// contentsOr ORs the queries of several -c values.
+func (c *Config) contentsOr(values []string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = c.contentQuery(v)
+	}
+	return strings.Join(parts, " OR ")
+}
+
+// checkAllOps validates -all: allinurl: and allintext: match the words of
+// one term and can't be ORed or mixed with operators, so inline -w and -c
+// values must be plain words and -c a single value. Terms from files are
+// checked as they are read, see allTerms.
+func (cfg *Config) checkAllOps() error {
+	if cfg.dictionary == "" && cfg.contents == "" {
+		return errors.New("-all needs -w or -c")
+	}
+	if cfg.contents != "" && !fileExists(cfg.contents) {
+		if strings.Contains(cfg.contents, ",") {
+			return errors.New("-all: allintext: can't OR several -c values; put them in a file to search them one by one")
+		}
+		if err := banshee.CheckAllTerm(cfg.contents); err != nil {
+			return fmt.Errorf("-all: -c %v", err)
+		}
+	}
+	if cfg.dictionary != "" && !isTermSource(cfg.dictionary) {
+		for _, t := range cfg.buildInurlQuery(cfg.dictionary) {
+			if err := banshee.CheckAllTerm(t); err != nil {
+				return fmt.Errorf("-all: -w %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// allTerms drops, with a warning, the terms read from a file that can't
+// go with allinurl: or allintext: under -all.
+func (c *Config) allTerms(terms []string) []string {
+	if !c.allOps {
+		return terms
+	}
+	kept := terms[:0:0]
+	for _, t := range terms {
+		if err := banshee.CheckAllTerm(t); err != nil {
+			logErr("[!] -all: skipping term %q: %v", t, err)
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
+
+func (c *Config) buildInurlQuery(dict string) []string {
+	// Return the raw terms; each is wrapped as inurl:"term" later per request
+	// to avoid awkward OR behavior.
+	var terms []string
+	if fileExists(dict) {
+		lines := c.readTermFile(dict)
+		for _, s := range lines {
+			if t := banshee.CleanTerm(s); t != "" {
+				terms = append(terms, t)
+			}
+		}
+	} else if strings.Contains(dict, ",") {
+		for _, s := range strings.Split(dict, ",") {
+			if t := banshee.CleanTerm(s); t != "" {
+				terms = append(terms, t)
+			}
+		}
+	} else {
+		if t := banshee.CleanTerm(dict); t != "" {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// streamTerms reads dictionary terms from path (see openTerms) and calls fn
+// with batches of up to size terms, without holding the whole file in
+// memory. It stops early when fn returns false.
+func (c *Config) streamTerms(path string, size int, fn func([]string) bool) error {
+	f, err := openTerms(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if size < 1 {
+		size = 1
+	}
+	batch := make([]string, 0, size)
+	sc := newLineScanner(f)
+	n, dropped := 0, 0
+	// fingerprints keep the deduplication cheap on huge wordlists
+	seen := fpSet{}
+	for sc.Scan() {
+		n++
+		if t := banshee.CleanTerm(stripComment(sc.Text())); t != "" {
+			if seen.add(t) {
+				batch = append(batch, t)
+			} else {
+				dropped++
+			}
+		} else if strings.TrimSpace(sc.Text()) != "" {
+			dropped++
+		}
+		if len(batch) == size {
+			if !fn(batch) {
+				return nil
+			}
+			batch = make([]string, 0, size)
+		}
+	}
+	if err := scanErr(sc, path, n); err != nil {
+		return err
+	}
+	c.reportDropped(path, dropped)
+	if len(batch) > 0 {
+		fn(batch)
+	}
+	return nil
+}
+
+// countTerms counts the non-empty terms in path with a streaming pass, for
+// progress reporting.
+func countTerms(path string) int {
+	f, err := openTerms(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	sc := newLineScanner(f)
+	seen := fpSet{}
+	for sc.Scan() {
+		if t := banshee.CleanTerm(stripComment(sc.Text())); t != "" {
+			seen.add(t)
+		}
+	}
+	return len(seen)
+}
+
+// staticExtensions is the built-in set dropped by -no-static.
+var staticExtensions = []string{
+	"css", "js", "map", "png", "jpg", "jpeg", "gif", "svg", "ico", "webp", "bmp",
+	"woff", "woff2", "ttf", "otf", "eot",
+}
+
+func buildExtensionFilter(list string, static bool) map[string]struct{} {
+	set := map[string]struct{}{}
+	if static {
+		for _, e := range staticExtensions {
+			set[e] = struct{}{}
+		}
+	}
+	for _, e := range splitList(list) {
+		e = strings.ToLower(strings.TrimPrefix(e, "."))
+		if e != "" {
+			set[e] = struct{}{}
+		}
+	}
+	return set
+}
+
+// --- IO helpers ---
+
+// splitList expands a flag value that is either a file (one entry per line),
+// a comma-separated list, or a single value.
+func splitList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	if fileExists(v) {
+		lines := readListFile(v)
+		return lines
+	}
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func fileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+// maxLineSize caps the length of a single line in input files (-max-line-size).
+var maxLineSize = 10 << 20
+
+// newLineScanner returns a line scanner that accepts lines up to maxLineSize
+// instead of bufio's 64KB default. Files saved by Windows tools read the
+// same as others: a leading UTF-8 byte order mark is skipped, and the
+// scanner drops the \r of CRLF line ends.
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(len(utf8BOM)); err == nil && string(b) == utf8BOM {
+		br.Discard(len(utf8BOM))
+	}
+	sc := bufio.NewScanner(br)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return sc
+}
+
+// utf8BOM is the byte order mark some editors put at the start of UTF-8
+// files.
+const utf8BOM = "\uFEFF"
+
+// scanErr returns sc's error, naming the offending line when it was too long.
+// n is the number of lines read successfully.
+func scanErr(sc *bufio.Scanner, path string, n int) error {
+	err := sc.Err()
+	if errors.Is(err, bufio.ErrTooLong) {
+		return fmt.Errorf("%s: line %d is longer than %d bytes (raise -max-line-size): %w", path, n+1, maxLineSize, err)
+	}
+	return err
+}
+
+func readLines(p string) ([]string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []string
+	sc := newLineScanner(f)
+	n := 0
+	for sc.Scan() {
+		n++
+		s := strings.TrimSpace(sc.Text())
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out, scanErr(sc, p, n)
+}
+
+// readTermFile reads a -w, -e, -c or -x list file: lines starting with #
+// are skipped, a trailing # comment is cut off, and repeated entries are
+// dropped, keeping the first.
+func (c *Config) readTermFile(p string) []string {
+	lines := readListFile(p)
+	out := lines[:0]
+	seen := map[string]bool{}
+	for _, l := range lines {
+		if l = stripComment(l); l != "" && !seen[l] {
+			seen[l] = true
+			out = append(out, l)
+		}
+	}
+	c.reportDropped(p, len(lines)-len(out))
+	return out
+}
+
+// stripComment removes a # comment from a list file line: the whole line
+// when it starts with #, otherwise everything from the first unescaped #.
+// \# stands for a literal #.
+func stripComment(l string) string {
+	l = strings.TrimSpace(l)
+	if !strings.Contains(l, "#") {
+		return l
+	}
+	if strings.HasPrefix(l, "#") {
+		return ""
+	}
+	var b strings.Builder
+	for i := 0; i < len(l); i++ {
+		switch {
+		case l[i] == '\\' && i+1 < len(l) && l[i+1] == '#':
+			b.WriteByte('#')
+			i++
+		case l[i] == '#':
+			return strings.TrimSpace(b.String())
+		default:
+			b.WriteByte(l[i])
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// droppedReported holds the files reportDropped has reported, since some
+// lists are read more than once.
+var droppedReported sync.Map
+
+// reportDropped notes, under -v and once per file, how many comment and
+// duplicate lines of a list file were skipped.
+func (c *Config) reportDropped(p string, n int) {
+	if n == 0 || !c.verbose {
+		return
+	}
+	if _, dup := droppedReported.LoadOrStore(p, true); !dup {
+		logv(true, "%s: skipped %d comment or duplicate line(s)", p, n)
+	}
+}
+
+// readListFile reads an input list file, reporting (rather than silently
+// dropping) read errors. Lines read before an error are still returned.
+func readListFile(p string) []string {
+	lines, err := readLines(p)
+	if err != nil {
+		logErr("[!] %v", err)
+	}
+	return lines
+}
+
+// emit applies the post-collection steps (probing, ...) to URL results and
+// writes them to -o or stdout, labelled or grouped by term when asked to.
+func (c *Config) emit(ctx context.Context, res []result) {
+	if c.count != nil {
+		// -count: nothing is written until the target is done
+		for _, r := range res {
+			c.count.add(c.dedupeKey(r.url))
+		}
+		return
+	}
+	if c.seen != nil {
+		fresh := res[:0:0]
+		for _, r := range res {
+			if c.seen.Add(c.resultKey(r)) {
+				fresh = append(fresh, r)
+			}
+		}
+		res = fresh
+	}
+	res = c.applyBlacklist(c.gfFilter(res))
+	lines := make([]string, len(res))
+	for i, r := range res {
+		lines[i] = r.url
+	}
+	if c.probe {
+		// dropped (dead) URLs come back as ""
+		lines = c.probeURLs(ctx, lines)
+	}
+	if c.cacheCheckOn && ctx.Err() == nil {
+		urls := make([]string, len(res))
+		for i, r := range res {
+			urls[i] = r.url
+		}
+		lines = c.cacheCheck(ctx, urls, lines)
+	}
+	if c.verbose {
+		for i, r := range res {
+			if lines[i] != "" {
+				logv(true, "%s <- %s", r.url, r.query)
+			}
+		}
+	}
+	if hostsRun != nil {
+		hostsRun.add(res, lines)
+		return
+	}
+	if (c.probe || c.cacheCheckOn) && c.outputPath != "" && !c.jsonOutput {
+		// -o stays a plain URL list for other tools: the annotations are
+		// shown instead (JSON has its own field for them)
+		for i, r := range res {
+			if lines[i] != "" {
+				fmt.Fprintln(infoOut, lines[i])
+				lines[i] = r.url
+			}
+		}
+	}
+	switch {
+	case c.jsonOutput:
+		out := make([]string, 0, len(lines))
+		for i, r := range res {
+			if lines[i] == "" {
+				continue
+			}
+			jr := jsonResult{URL: r.url, Target: c.target, Term: r.term, Ext: r.ext, Query: r.query}
+			if c.multiEngine() || r.engine != c.engine {
+				// several engines, or a -sources result
+				jr.Engine = r.engine
+			}
+			jr.Ports = c.shodanPorts
+			jr.Probe = strings.TrimSpace(strings.TrimPrefix(lines[i], r.url))
+			b, _ := json.Marshal(jr)
+			out = append(out, string(b))
+		}
+		c.output().outputOrPrintUnique(out, c.outputPath, keyFunc{"json-url", func(l string) string {
+			var jr jsonResult
+			if json.Unmarshal([]byte(l), &jr) != nil {
+				return l
+			}
+			if c.labelTerms || c.groupByTerm {
+				return jr.Term + "\t" + c.dedupeKey(jr.URL)
+			}
+			return c.dedupeKey(jr.URL)
+		}})
+	case c.groupByTerm:
+		var order []string
+		groups := map[string][]string{}
+		for i, r := range res {
+			if lines[i] == "" {
+				continue
+			}
+			if _, ok := groups[r.term]; !ok {
+				order = append(order, r.term)
+			}
+			groups[r.term] = append(groups[r.term], lines[i])
+		}
+		for _, t := range order {
+			g := banshee.UniqueByKey(groups[t], c.lineKey)
+			sort.Strings(g)
+			if t != "" {
+				g = append([]string{"# " + t}, g...)
+			}
+			c.output().writeUnique(g, c.outputPath, keyFunc{"url", c.lineKey})
+		}
+	case c.labelTerms:
+		labelled := make([]string, 0, len(lines))
+		for i, r := range res {
+			if lines[i] != "" {
+				labelled = append(labelled, r.term+"\t"+lines[i])
+			}
+		}
+		c.output().outputOrPrintUnique(labelled, c.outputPath, keyFunc{"term-url", func(l string) string {
+			t, u, _ := strings.Cut(l, "\t")
+			return t + "\t" + c.lineKey(u)
+		}})
+	default:
+		c.output().outputOrPrintUnique(lines, c.outputPath, keyFunc{"url", c.lineKey})
+	}
+}
+
+// --- HTTP client and requests ---
+
+// clientTimeout bounds every request made with buildHTTPClient's client.
+// API requests are usually cut short by -request-timeout first.
+const clientTimeout = 30 * time.Second
+
+// buildHTTPClient returns the client for API and other requests, proxied
+// as proxyFunc decides for proxyURL (-proxy) and mode (-proxy-mode).
+func buildHTTPClient(proxyURL, mode string) (*http.Client, error) {
+	proxy, err := proxyFunc(proxyURL, mode)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   20 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          50,
+		IdleConnTimeout:       60 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   clientTimeout,
+	}, nil
+}
+
+// notFound reports the terms of a completed search that got no result,
+// as a verbose one-liner each and, with -no-results-file, as
+// "target<TAB>mode<TAB>term" lines in that file.
+func (c *Config) notFound(ext string, hits map[string]bool) {
+	var misses []string
+	seen := map[string]bool{}
+	for _, r := range banshee.BuildQueries(c.querySpec(ext)) {
+		if hits[r.Term] || seen[r.Term] {
+			continue
+		}
+		seen[r.Term] = true
+		if r.Term == "" {
+			logv(c.verbose, "No results: %s (%s)", c.target, c.modeName())
+		} else {
+			logv(c.verbose, "No results: %s (%s %s)", c.target, c.modeName(), r.Term)
+		}
+		misses = append(misses, c.target+"\t"+c.modeName()+"\t"+r.Term)
+	}
+	if c.noResultsFile != "" && len(misses) > 0 {
+		c.output().writeUnique(misses, c.noResultsFile, exactKey)
+	}
+}
+
+func (c *Config) showContentInFile() {
+	// This only prints when contents set; kept minimal
+	if c.contents != "" {
+		logv(c.verbose, "Files found containing: %s", c.contents)
+	}
+}
+
+// linkFilter is the banshee.Filter for the current target and flags.
+func (c *Config) linkFilter() banshee.Filter {
+	return banshee.Filter{
+		Target:          c.target,
+		RawURLs:         c.rawURLs,
+		StripParams:     c.stripSet,
+		ExcludePaths:    c.excludePaths,
+		ExcludeHosts:    c.excludeHosts,
+		ParamsOnly:      c.paramsOnly,
+		DropExtensions:  c.filterExts,
+		UniqueParams:    c.uniqueParams,
+		LooseDedupe:     c.dedupeLoose,
+		CaseInsensitive: c.dedupeCI,
+	}
+}
+
+// scopeLinks applies every per-link filter to raw links. Extension mode
+// asked for its filetypes explicitly, so -fe does not apply there.
+func (c *Config) scopeLinks(links []string, extMode bool) []string {
+	return c.linkFilter().Apply(links, extMode)
+}
+
+// dedupeKey is the comparison key for result URLs. With -unique-params only
+// the parameter names count, and with -dedupe-loose the scheme and a leading
+// "www." are ignored.
+func (c *Config) dedupeKey(u string) string {
+	return c.linkFilter().Key(u)
+}
+
+// lineKey is dedupeKey for result lines printed to stdout, which under
+// -probe and -cache-check carry annotations after the URL: the same URL
+// probed twice is one result. -group-by-term headers are kept whole.
+func (c *Config) lineKey(l string) string {
+	if strings.HasPrefix(l, "# ") {
+		return l
+	}
+	u, _, _ := strings.Cut(l, " ")
+	return c.dedupeKey(u)
+}
+
+// buildStripSet is the -strip-params set: the built-in tracking and session
+// parameters plus -strip-params-extra.
+func buildStripSet(extra string) map[string]struct{} {
+	return banshee.NewStripSet(splitList(extra))
+}
+
+// resultKey is the dedup key of a tagged result: its URL key, prefixed by
+// the term when results are labelled or grouped by term.
+func (c *Config) resultKey(r result) string {
+	k := c.dedupeKey(r.url)
+	if c.labelTerms || c.groupByTerm {
+		k = r.term + "\x00" + k
+	}
+	return k
+}
+
+// uniqueTagged deduplicates tagged results by URL, or by (term, URL) when
+// results are labelled or grouped by term.
+func (c *Config) uniqueTagged(in []result) []result {
+	idx := make(map[string]int, len(in))
+	out := make([]result, 0, len(in))
+	for _, r := range in {
+		if r.url == "" {
+			continue
+		}
+		k := c.resultKey(r)
+		if i, ok := idx[k]; ok {
+			if r.url != out[i].url && banshee.CanonicalScore(r.url) > banshee.CanonicalScore(out[i].url) {
+				out[i] = r
+			}
+			continue
+		}
+		idx[k] = len(out)
+		out = append(out, r)
+	}
+	return out
+}
+
+func uniqueStrings(in []string) []string {
+	seen := make(map[string]struct{}, len(in))
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		if s == "" {
+			continue
+		}
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	return out
+}
+
+// delayRange is the -d value: a fixed delay in seconds, or with MIN-MAX a
+// range each delay is drawn from uniformly. The zero value leaves the delay
+// to the adaptive logic.
+type delayRange struct{ min, max float64 }
+
+func (r *delayRange) String() string {
+	if r == nil {
+		return "0"
+	}
+	v := strconv.FormatFloat(r.max, 'g', -1, 64)
+	if r.isRange() {
+		v = strconv.FormatFloat(r.min, 'g', -1, 64) + "-" + v
+	}
+	return v
+}
+
+func (r *delayRange) Set(v string) error {
+	lo, hi, isRange := strings.Cut(strings.TrimSpace(v), "-")
+	min, err := strconv.ParseFloat(strings.TrimSpace(lo), 64)
+	if err != nil {
+		return fmt.Errorf("want seconds or MIN-MAX, got %q", v)
+	}
+	max := min
+	if isRange {
+		if max, err = strconv.ParseFloat(strings.TrimSpace(hi), 64); err != nil {
+			return fmt.Errorf("want seconds or MIN-MAX, got %q", v)
+		}
+	}
+	if min < 0 || max < min {
+		return fmt.Errorf("invalid range %q: want 0 <= MIN <= MAX", v)
+	}
+	r.min, r.max = min, max
+	return nil
+}
+
+// set reports whether -d fixes the delay, turning the adaptive one off.
+func (r delayRange) set() bool { return r.max > 0 }
+
+func (r delayRange) isRange() bool { return r.max > r.min }
+
+// draw returns the delay before the next request.
+func (r delayRange) draw() float64 {
+	return r.min + (r.max-r.min)*rand.Float64()
+}
+
+func (c *Config) delayControl(st *runState) {
+	d := st.dynamicDelay
+	if c.delay.set() {
+		d = c.delay.draw()
+		logDebug(c.debug, "[debug] delay %.2fs", d)
+	}
+	if d > 0 && c.delayJitter > 0 && !c.delay.isRange() {
+		// up to delayJitter*d extra, so timing isn't machine-regular
+		d += d * c.delayJitter * rand.Float64()
+	}
+	if d > 0 {
+		sleep(time.Duration(d * float64(time.Second)))
+	}
+}
+
+// sleep is time.Sleep, replaced by tests.
+var sleep = time.Sleep
+
+// initialDelay is the adaptive delay a run starts with, within the bounds.
+func (c *Config) initialDelay() float64 {
+	return c.clampDelay(0.25)
+}
+
+func (c *Config) clampDelay(d float64) float64 {
+	if d < c.minDelay {
+		d = c.minDelay
+	}
+	if c.maxDelay > 0 && d > c.maxDelay {
+		d = c.maxDelay
+	}
+	return d
+}
+
+// adjustDelay moves the adaptive delay by step, staying within
+// -min-delay/-max-delay. It does nothing when -d fixes the delay.
+func (c *Config) adjustDelay(st *runState, step float64) {
+	if c.delay.set() {
+		return
+	}
+	st.dynamicDelay = c.clampDelay(st.dynamicDelay + step)
+	c.stats.delayNow(c.engine, st.dynamicDelay)
+}
+
+func (c *Config) readDomainsFile(ctx context.Context) error {
+	lines, err := readLines(c.domainsFile)
+	if errors.Is(err, bufio.ErrTooLong) {
+		return fmt.Errorf("[!] Error, %w", err)
+	} else if err != nil {
+		return fmt.Errorf("[!] Error, file not found: %s", c.domainsFile)
+	}
+	if !c.noGlobalDedupe {
+		// example.com and sub.example.com often match the same URLs
+		c.seen = NewSafeSet(c.output().compact)
+	}
+	lines = c.selectTargets(lines)
+	total := len(lines)
+	if !c.silent {
+		initStatus()
+		defer clearStatus()
+	}
+	n := 0
+	for i, line := range lines {
+		if ctx.Err() != nil {
+			c.unprocessed(lines[i:])
+			return ctx.Err()
+		}
+		n++
+		t, err := banshee.NormalizeTarget(line)
+		if err != nil {
+			logErr("[!] %s: skipping %v", c.domainsFile, err)
+			lg.event("target_invalid", "target", line, "error", err.Error())
+			summaryRun.row(summaryRow{Target: line, Status: "invalid"})
+			continue
+		}
+		c2 := *c
+		c2.target = t
+		c2.track = &targetTracker{}
+		if c.countOnly {
+			c2.count = newTargetCount()
+		}
+		if !c.silent {
+			setStatus(fmt.Sprintf("[%d/%d] processing %s (results so far: %s, requests: %s)",
+				n, total, c2.target, commas(savedResults()), commas(int64(c.quotaState().Requests))))
+		}
+
+		// Each target gets its own deadline under -domain-timeout. On expiry
+		// the mode returns what it found so far, which is written as usual.
+		dctx, cancel := ctx, context.CancelFunc(func() {})
+		if c.domainTimeout > 0 {
+			dctx, cancel = context.WithTimeout(ctx, c.domainTimeout)
+		}
+		lg.event("target_start", "target", c2.target)
+		manifestRun.target(c2.target)
+		before := savedResults()
+		row := c2.startSummary()
+		c2.runTarget(dctx)
+		lg.event("target_done", "target", c2.target, "results", savedResults()-before)
+		c2.endSummary(&row)
+		timedOut := errors.Is(dctx.Err(), context.DeadlineExceeded)
+		cancel()
+		if ctx.Err() != nil {
+			// the current target was cut short too
+			row.Status = "interrupted"
+			summaryRun.row(row)
+			c.unprocessed(lines[i:])
+			return ctx.Err()
+		}
+		row.Status = "ok"
+		if timedOut {
+			row.Status = "timeout"
+			logErr("[!] %s: timed out after %s, skipping", c2.target, c.domainTimeout)
+			if c.skippedFile != "" {
+				c.output().writeUnique([]string{c2.target}, c.skippedFile, exactKey)
+			}
+		} else if c2.erroredOut() {
+			// left for -resume, like a timed-out target
+			row.Status = "errors"
+			flushOutput(c.outputPath)
+		} else if !keysRanOut.Load() {
+			// results first, so a crash can't record a target whose
+			// results were still buffered
+			c2.writeCount()
+			flushOutput(c.outputPath)
+			if outputFailed() != nil {
+				summaryRun.row(row)
+				c.unprocessed(lines[i+1:])
+				return fmt.Errorf("[!] stopped after %s: results can't be saved", c2.target)
+			}
+			resumeRun.markDone(c2.target)
+		} else {
+			row.Status = "no-keys"
+		}
+		summaryRun.row(row)
+	}
+	return nil
+}
+
+// selectTargets returns the targets of the -f file to process: blank lines
+// and -resume'd targets dropped, the first -skip left out, shuffled with
+// -shuffle and cut to -dl.
+func (c *Config) selectTargets(lines []string) []string {
+	var targets []string
+	for _, l := range lines {
+		if t := strings.TrimSpace(l); t != "" {
+			targets = append(targets, t)
+		}
+	}
+	all := len(targets)
+	if c.skipTargets > 0 {
+		targets = targets[min(c.skipTargets, len(targets)):]
+	}
+	kept, done := targets[:0], 0
+	for _, t := range targets {
+		if n, err := banshee.NormalizeTarget(t); err == nil && resumeRun.completed(n) {
+			done++
+			continue
+		}
+		kept = append(kept, t)
+	}
+	targets = kept
+	if done > 0 {
+		logv(true, "[*] -resume: skipping %d targets completed earlier", done)
+	}
+	if c.shuffle {
+		rand.Shuffle(len(targets), func(i, j int) { targets[i], targets[j] = targets[j], targets[i] })
+	}
+	if c.domainLimit > 0 && len(targets) > c.domainLimit {
+		targets = targets[:c.domainLimit]
+	}
+	if c.skipTargets > 0 || c.domainLimit > 0 {
+		logv(true, "[*] processing %d of %d targets, %d left out by -skip/-dl", len(targets), all, all-done-len(targets))
+	}
+	return targets
+}
+
+// unprocessed reports the targets a cancelled -f run didn't finish and
+// appends them to -skipped-file, so the next run can pick them up.
+func (c *Config) unprocessed(lines []string) {
+	var left []string
+	for _, l := range lines {
+		if t, err := banshee.NormalizeTarget(l); err == nil && !resumeRun.completed(t) {
+			left = append(left, t)
+		}
+	}
+	if len(left) == 0 {
+		return
+	}
+	logErr("[!] %d targets not processed", len(left))
+	if c.skippedFile != "" {
+		c.output().writeUnique(left, c.skippedFile, exactKey)
+	}
+}
+
+// runTarget runs the selected mode against c.target for a -f run.
+func (c *Config) runTarget(ctx context.Context) {
+	if c.modeList != nil {
+		c.runModes(ctx)
+		return
+	}
+	if c.dork != "" {
+		emit := func(res []result) { c.emit(ctx, res) }
+		if res := c.urlRun(ctx, "", emit); len(res) > 0 {
+			emit(res)
+		}
+	} else if c.extension != "" {
+		c.extensionAttack(ctx)
+	} else if c.dictionary != "" {
+		c.dictionaryAttack(ctx)
+	} else if c.subdomainMode {
+		c.subdomainAttack(ctx)
+	} else if c.contents != "" {
+		c.contentsAttack(ctx)
+	}
+}
+
+// uniqueReqs drops requests whose query repeats an earlier one.
+func uniqueReqs(in []searchReq) []searchReq {
+	seen := make(map[string]struct{}, len(in))
+	out := in[:0]
+	for _, r := range in {
+		if _, ok := seen[r.query]; ok {
+			continue
+		}
+		seen[r.query] = struct{}{}
+		out = append(out, r)
+	}
+	return out
+}
+
+// forEachReq runs fn for every request, -concurrency at a time. With a
+// single worker requests go out back to back as before; with several, each
+// worker waits the request delay between its own requests.
+func (c *Config) forEachReq(ctx context.Context, st *runState, reqs []searchReq, fn func(searchReq)) {
+	if c.concurrency <= 1 {
+		for _, r := range reqs {
+			if ctx.Err() != nil {
+				return
+			}
+			fn(r)
+		}
+		return
+	}
+	ch := make(chan searchReq)
+	var wg sync.WaitGroup
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			first := true
+			for r := range ch {
+				if !first {
+					c.delayControl(st)
+				}
+				first = false
+				if ctx.Err() == nil {
+					fn(r)
+				}
+			}
+		}()
+	}
+feed:
+	for _, r := range reqs {
+		select {
+		case ch <- r:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(ch)
+	wg.Wait()
+}
+
+// dorkRun is the central querying routine. When it ran all its queries,
+// the terms that got no result are passed to notFound.
+func (c *Config) dorkRun(ctx context.Context, ext string) []result {
+	if c.hopeless() {
+		return nil
+	}
+	var st *runState
+	if len(c.engines) > 1 {
+		st = c.mergeEngines(ctx, ext)
+	} else {
+		st = c.searchPages(ctx, ext)
+	}
+	if !st.failed && ctx.Err() == nil {
+		c.notFound(ext, st.hits)
+	}
+	if len(st.store) == 0 {
+		return nil
+	}
+	return st.store
+}
+
+// urlRun is dorkRun for the modes that write the URLs found as they are:
+// under -flush-every, every -flush-every results go to flush as the pages
+// come in rather than being held until the run ends, and only the rest is
+// returned. Modes that work on the full result set (subdomains, -recursion,
+// -permute, -download) call dorkRun.
+func (c *Config) urlRun(ctx context.Context, ext string, flush func([]result)) []result {
+	if c.flushEvery > 0 && c.downloadDir == "" {
+		c2 := *c
+		c2.flush = flush
+		c = &c2
+	}
+	return c.dorkRun(ctx, ext)
+}
+
+// querySpec describes the searches of the current mode; ext selects
+// extension mode.
+func (c *Config) querySpec(ext string) banshee.QuerySpec {
+	spec := banshee.QuerySpec{
+		Target:        c.target,
+		Subdomains:    c.includeSubdomains,
+		Exclusions:    c.excludeTargets,
+		Dork:          c.dork,
+		Extension:     ext,
+		Contents:      c.contents,
+		ContentsQuery: c.inFile,
+		Wildcard:      c.wildcard,
+		All:           c.allOps,
+	}
+	if c.dictionary != "" {
+		spec.Terms = c.inUrl
+		if len(spec.Terms) == 0 {
+			spec.Terms = []string{c.dictionary}
+		}
+	}
+	return spec
+}
+
+// searchPages pages through the queries of one engine. The returned state
+// holds the results, the terms that had any and whether the run was cut
+// short by errors, cancellation or running out of keys.
+func (c *Config) searchPages(ctx context.Context, ext string) *runState {
+	st := &runState{dynamicDelay: c.initialDelay(), hits: map[string]bool{}}
+	c.stats.delayNow(c.engine, st.dynamicDelay)
+	page := 0
+	pages := c.pages
+	if pages == 0 {
+		pages = 10
+	}
+
+	for page < pages {
+		if ctx.Err() != nil {
+			st.failed = true
+			return st
+		}
+
+		startIdx := page*10 + 1 // CSE is 1-based
+
+		// a page is retried once per key when its requests fail
+		var triedKeys int
+		maxTries := c.provider.QuotaState().Keys
+
+		for triedKeys < maxTries {
+			if ctx.Err() != nil || c.hopeless() {
+				st.failed = true
+				return st
+			}
+			if c.outOfKeys() {
+				st.failed = true
+				return st
+			}
+
+			var urls []searchReq
+			for _, r := range banshee.BuildQueries(c.querySpec(ext)) {
+				urls = append(urls, searchReq{term: r.Term, query: c.provider.Rewrite(r.Query)})
+			}
+
+			// adapted queries can collapse (filetype: and ext: are both
+			// mime: on Yandex); send each distinct one once
+			urls = uniqueReqs(urls)
+			if len(st.rejected) > 0 {
+				kept := urls[:0]
+				for _, u := range urls {
+					if !st.rejected[u.query] {
+						kept = append(kept, u)
+					}
+				}
+				urls = kept
+			}
+
+			// one delay between consecutive attempts, none before the
+			// first or after the last
+			if st.sent {
+				c.delayControl(st)
+			}
+			st.sent = true
+
+			var combined []result
+			var respErr error
+			var mu sync.Mutex // guards combined and respErr across workers
+			c.forEachReq(ctx, st, urls, func(u searchReq) {
+				links, err := c.searchReq(ctx, u.query, page, startIdx)
+				if errors.Is(err, banshee.ErrInvalidQuery) {
+					// another key would get the same answer: drop the query
+					// for the remaining pages and don't count it as a failure
+					logErr("[!] %s rejected query %q: %v", c.engine, u.query, err)
+					mu.Lock()
+					if st.rejected == nil {
+						st.rejected = map[string]bool{}
+					}
+					st.rejected[u.query] = true
+					mu.Unlock()
+					return
+				}
+				if err != nil {
+					mu.Lock()
+					respErr = err
+					mu.Unlock()
+					return
+				}
+				links = c.scopeLinks(links, ext != "")
+				mu.Lock()
+				for _, l := range links {
+					combined = append(combined, result{url: l, term: u.term, query: u.query, engine: c.engine, ext: ext})
+				}
+				mu.Unlock()
+			})
+			if ctx.Err() != nil {
+				// keep what the page's finished requests found; the caller
+				// writes it out with the rest
+				st.store = c.uniqueTagged(append(st.store, combined...))
+				st.failed = true
+				return st
+			}
+
+			combined = c.uniqueTagged(combined)
+			lg.event("page", "engine", c.engine, "target", c.target, "page", page+1, "results", len(combined))
+			if len(combined) > 0 {
+				c.track.pageDone()
+				for _, r := range combined {
+					st.hits[r.term] = true
+				}
+				st.failed = false
+				st.store = c.uniqueTagged(append(st.store, combined...))
+				if c.flush != nil && len(st.store) >= c.flushEvery {
+					// bounded-memory mode: hand the batch to the output now;
+					// the sinks' fingerprints dedupe it against later ones
+					c.flush(st.store)
+					st.flushed += len(st.store)
+					st.store = nil
+				}
+				st.resultsFound = true
+				st.noResultCounter = 0
+				st.requestCounter++
+				c.track.record(true)
+				c.adjustDelay(st, -0.05)
+				break
+			}
+
+			if respErr != nil {
+				logv(c.verbose, "Error: %v", respErr)
+				lg.event("query_error", "engine", c.engine, "target", c.target, "error", respErr.Error())
+				var bad *banshee.BadResponseError
+				if c.debug && errors.As(respErr, &bad) {
+					logDebug(true, "[debug] %s response body (status %d):\n%s", c.engine, bad.Status, bad.Body)
+				}
+				// a proxy or error page in the way is as likely to go away
+				// with a slower pace as a rate limit
+				if errors.Is(respErr, banshee.ErrRateLimited) || errors.Is(respErr, banshee.ErrBadResponse) {
+					c.adjustDelay(st, 1)
+				}
+				triedKeys++
+				st.failed = true
+			} else {
+				c.track.pageDone()
+				st.failed = false
+				st.noResultCounter++
+				c.track.record(false)
+				triedKeys = maxTries
+				c.adjustDelay(st, 0.1)
+			}
+		}
+
+		if !st.resultsFound {
+			// the page's last try may have retired the last key
+			if st.failed {
+				c.outOfKeys()
+			}
+			break
+		}
+		st.resultsFound = false
+		page++
+	}
+
+	return st
+}
+
+// outOfKeys reports whether every key of the engine is spent, logging it
+// and recording it for the exit code if so.
+func (c *Config) outOfKeys() bool {
+	if c.provider.QuotaState().Usable > 0 {
+		return false
+	}
+	if projectExhausted.Load() {
+		logErr("No valid API keys remaining: project quota exhausted.")
+	} else {
+		logErr("No valid API keys remaining.")
+	}
+	keysRanOut.Store(true)
+	return true
+}
+
+// decodeRetries is how many times a request answered with an unreadable
+// body is sent again, with another key when there is one, before its
+// error counts against the page.
+const decodeRetries = 2
+
+// searchReq sends query for the page starting at startIdx, recording each
+// attempt with -audit. Truncated or garbage responses are retried here
+// rather than costing the page a key attempt, or worse, being taken for a
+// page without results when the page's other queries found some.
+func (c *Config) searchReq(ctx context.Context, query string, page, startIdx int) ([]string, error) {
+	var avoid string
+	for attempt := 0; ; attempt++ {
+		if err := c.pace.wait(ctx); err != nil {
+			return nil, err
+		}
+		var info banshee.SearchInfo
+		sctx := banshee.WithSearchInfo(ctx, &info)
+		if avoid != "" {
+			sctx = banshee.WithoutKey(sctx, avoid)
+		}
+		links, err := c.provider.Search(sctx, banshee.Query{Text: query, Start: startIdx})
+		c.stats.requestDone(c.engine, err)
+		if !errors.Is(err, banshee.ErrBadResponse) || attempt == decodeRetries {
+			c.track.requestDone(err)
+		}
+		if auditRun != nil {
+			e := auditEntry{Target: c.target, Mode: c.modeName(), Engine: c.engine, Query: query,
+				Page: page + 1, Start: startIdx, Key: keyFingerprint(info.Key), Status: info.Status, Results: len(links)}
+			if err != nil {
+				e.Error = err.Error()
+			}
+			auditRun.record(e)
+		}
+		bad := errors.Is(err, banshee.ErrBadResponse)
+		if attempt > 0 {
+			c.stats.decodeRetried(c.engine, bad && attempt == decodeRetries)
+		}
+		if !bad || attempt == decodeRetries || ctx.Err() != nil {
+			return links, err
+		}
+		logv(c.verbose, "Retrying %q after an unreadable response: %v", query, err)
+		lg.event("decode_retry", "engine", c.engine, "target", c.target, "query", query, "error", err.Error())
+		avoid = info.Key
+	}
+}
+
+func (c *Config) dictionaryAttack(ctx context.Context) {
+	c.announce()
+	if isTermSource(c.dictionary) {
+		c.dictionaryFileAttack(ctx)
+		return
+	}
+	if len(c.inUrl) == 0 {
+		c.inUrl = c.buildInurlQuery(c.dictionary)
+	}
+	res := c.urlRun(ctx, "", func(res []result) { c.emit(ctx, res) })
+	if wb := c.waybackLinks(ctx, false); len(wb) > 0 {
+		res = c.uniqueTagged(append(res, waybackResults(wb, matchTerms(c.inUrl))...))
+	}
+	if len(res) > 0 {
+		c.emit(ctx, res)
+	}
+}
+
+// dictionaryFileAttack streams a dictionary file, or built-in wordlists, in
+// batches of -term-batch terms; each batch is its own dorkRun whose results
+// are written as soon as it finishes, so memory use doesn't grow with the
+// wordlist.
+func (c *Config) dictionaryFileAttack(ctx context.Context) {
+	total := countTerms(c.dictionary)
+	done := 0
+	// fetched once, matched against every batch
+	wb := c.waybackLinks(ctx, false)
+	err := c.streamTerms(c.dictionary, c.termBatch, func(batch []string) bool {
+		c2 := *c
+		c2.inUrl = c.allTerms(batch)
+		if len(c2.inUrl) == 0 {
+			done += len(batch)
+			return ctx.Err() == nil
+		}
+		res := c2.urlRun(ctx, "", func(res []result) { c2.emit(ctx, res) })
+		if len(wb) > 0 {
+			res = c.uniqueTagged(append(res, waybackResults(wb, matchTerms(batch))...))
+		}
+		done += len(batch)
+		if total > len(batch) {
+			logv(c.verbose, "[*] %s: %d/%d terms processed", c.target, done, total)
+		}
+		if len(res) > 0 {
+			c2.emit(ctx, res)
+		}
+		return ctx.Err() == nil
+	})
+	if err != nil {
+		logErr("[!] %v", err)
+	}
+}
+func (c *Config) extensionAttack(ctx context.Context) {
+	if c.groupByExt && !c.groupByTerm {
+		// an extension search's term is the extension
+		c2 := *c
+		c2.groupByTerm = true
+		c = &c2
+	}
+	var exts []string
+	if fileExists(c.extension) {
+		exts = c.readTermFile(c.extension)
+	} else if strings.Contains(c.extension, ",") {
+		for _, t := range strings.Split(c.extension, ",") {
+			if s := strings.TrimSpace(t); s != "" {
+				exts = append(exts, s)
+			}
+		}
+	} else if c.extension != "" {
+		exts = []string{strings.TrimSpace(c.extension)}
+	}
+
+	// Each extension is a separate dorkRun; with -workers > 1 they run on
+	// cloned Configs (own paging/delay state, shared key pool). Results are
+	// collected per extension index so the final order doesn't depend on
+	// scheduling.
+	workers := c.workers
+	if workers < 1 {
+		workers = 1
+	}
+	perExt := make([][]result, len(exts))
+	var outMu sync.Mutex // serializes -flush-every batches from the workers
+	flush := func(res []result) {
+		outMu.Lock()
+		defer outMu.Unlock()
+		c.emit(ctx, res)
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				run := c
+				if workers > 1 {
+					c2 := *c
+					run = &c2
+				}
+				logv(run.verbose, "Checking extension: %s", exts[i])
+				perExt[i] = run.urlRun(ctx, exts[i], flush)
+			}
+		}()
+	}
+feed:
+	for i := range exts {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// On cancellation perExt holds whatever each run collected; it is
+	// written like a complete result set, minus the downloads.
+	if wb := c.waybackLinks(ctx, true); len(wb) > 0 {
+		for i, ext := range exts {
+			for _, r := range waybackResults(wb, func(l string) (string, bool) {
+				return ext, hasExtension(l, ext)
+			}) {
+				r.ext = ext
+				perExt[i] = append(perExt[i], r)
+			}
+		}
+	}
+
+	var all []result
+	var downloads []downloadJob
+	for i, res := range perExt {
+		logv(c.verbose, "Extension %s: %d results", exts[i], len(res))
+		all = append(all, res...)
+		for _, r := range res {
+			downloads = append(downloads, downloadJob{url: r.url, ext: exts[i]})
+		}
+	}
+
+	if len(all) == 0 {
+		return
+	}
+	c.emit(ctx, c.uniqueTagged(all))
+	if c.downloadDir != "" && ctx.Err() == nil {
+		c.downloadFiles(ctx, downloads)
+	}
+}
+
+func (c *Config) performExtensionRequest(ctx context.Context, ext string) {
+	logv(c.verbose, "Checking extension: %s", ext)
+	res := c.dorkRun(ctx, ext)
+	if len(res) == 0 {
+		return
+	}
+	c.showContentInFile()
+	if c.outputPath != "" {
+		c.emit(ctx, res)
+	}
+}
+
+// subdomainAttack prints the subdomains found and returns them, including
+// those already printed for an earlier target.
+func (c *Config) subdomainAttack(ctx context.Context) []string {
+	c.announce()
+	res := c.dorkRun(ctx, "")
+	// Print subdomains (awk -F/ '{print $3}' | sort -u), keeping only hosts
+	// under the target's registered domain. hostSet maps each host to the
+	// sources that found it, depth to the -recursion round that first did.
+	apex := banshee.RegisteredDomain(c.target)
+	hostSet := map[string][]string{}
+	depth := map[string]int{}
+	round := 1
+	add := func(h, source string) {
+		if h == "" || !banshee.InScope(h, c.target, apex) {
+			return
+		}
+		if depth[h] == 0 {
+			depth[h] = round
+		}
+		for _, s := range hostSet[h] {
+			if s == source {
+				return
+			}
+		}
+		hostSet[h] = append(hostSet[h], source)
+	}
+	for _, r := range res {
+		add(banshee.HostOf(r.url), r.engine)
+	}
+	if c.sources["crtsh"] && ctx.Err() == nil {
+		ct, err := c.crtshHosts(ctx, c.target)
+		if err != nil {
+			logErr("[!] crt.sh unavailable, using search results only: %v", err)
+		}
+		for _, h := range ct {
+			add(h, "crtsh")
+		}
+	}
+	for _, l := range c.waybackLinks(ctx, false) {
+		add(banshee.HostOf(l), "wayback")
+	}
+	if c.sources["vt"] && ctx.Err() == nil {
+		vt, err := c.vtHosts(ctx, c.target)
+		if err != nil {
+			logErr("[!] VirusTotal: %v (using %d hosts fetched so far)", err, len(vt))
+		}
+		for _, h := range vt {
+			add(h, "vt")
+		}
+	}
+	if c.recursion > 0 && ctx.Err() == nil {
+		c.recurseSubdomains(ctx, depth, func(h, source string, d int) {
+			round = d
+			add(h, source)
+		})
+	}
+	if c.permuteFile != "" && len(hostSet) > 0 && ctx.Err() == nil {
+		known := make([]string, 0, len(hostSet))
+		for h := range hostSet {
+			known = append(known, h)
+		}
+		sort.Strings(known)
+		for _, h := range c.permuteHosts(ctx, known, apex) {
+			add(h, "permute")
+		}
+	}
+	if len(hostSet) == 0 {
+		return nil
+	}
+	hosts := make([]string, 0, len(hostSet))
+	for h := range hostSet {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	all := append([]string(nil), hosts...)
+	if c.count != nil {
+		for _, h := range hosts {
+			c.count.add(h)
+		}
+		return all
+	}
+	if c.seen != nil {
+		fresh := hosts[:0]
+		for _, h := range hosts {
+			if c.seen.Add(h) {
+				fresh = append(fresh, h)
+			}
+		}
+		hosts = fresh
+	}
+	lines := make([]string, len(hosts))
+	copy(lines, hosts)
+	var resolved []resolvedHost
+	if c.resolve {
+		// after cancellation every lookup fails at once, leaving the hosts
+		// marked unverified
+		resolved = c.resolveHosts(ctx, hosts)
+		lines = c.formatResolved(resolved, apex)
+		hosts = hosts[:0]
+		for _, r := range resolved {
+			hosts = append(hosts, r.host)
+		}
+	} else if c.relativeHosts {
+		for i, h := range hosts {
+			lines[i] = banshee.RelativeHost(h, apex)
+		}
+	}
+	if c.labelTerms && len(c.sources) > 0 {
+		for i, h := range hosts {
+			lines[i] = strings.Join(hostSet[h], ",") + "\t" + lines[i]
+		}
+	}
+	switch c.hostFormat {
+	case formatMassdns:
+		lines = massdnsLines(hosts)
+	case formatHosts:
+		lines = c.hostsLines(resolved)
+	}
+	if c.jsonOutput {
+		lines = c.hostsJSON(hosts, hostSet, depth, resolved)
+		c.output().outputOrPrintUnique(lines, c.outputPath, keyFunc{"json-host", jsonHostKey})
+		return all
+	}
+	key := exactKey
+	if c.resolve && c.hostFormat == "" {
+		key = keyFunc{"host", resolvedHostKey}
+	}
+	c.output().outputOrPrintUnique(lines, c.outputPath, key)
+	return all
+}
+
+// contentSearch is one search of a -c file: its term, as reported, and
+// its intext: part.
+type contentSearch struct {
+	term, query string
+}
+
+// maxEncodedQuery bounds the URL-encoded q value of a chunked -c query,
+// leaving room for the rest of the request URL under Google's limit of
+// about 2048 characters.
+const maxEncodedQuery = 1900
+
+// contentChunks turns the terms of a -c file into searches: one per term,
+// or with -chunk up to that many ORed together as
+// (intext:"a" OR intext:"b"), reported as "a,b" like an inline list. A
+// chunk is cut short when adding a term would make a query too long. Runs
+// that attribute results to single terms (-group-by-term, -label-terms)
+// and -all, whose allintext: can't be ORed, keep one search per term.
+func (c *Config) contentChunks(terms []string) []contentSearch {
+	if c.chunk <= 1 || c.groupByTerm || c.labelTerms || c.allOps {
+		out := make([]contentSearch, len(terms))
+		for i, t := range terms {
+			out[i] = contentSearch{term: t, query: c.contentQuery(t)}
+		}
+		return out
+	}
+	search := func(chunk []string) contentSearch {
+		return contentSearch{term: strings.Join(chunk, ","), query: "(" + c.contentsOr(chunk) + ")"}
+	}
+	fits := func(chunk []string) bool {
+		c2 := *c
+		s := search(chunk)
+		c2.contents, c2.inFile = s.term, s.query
+		for _, r := range banshee.BuildQueries(c2.querySpec("")) {
+			if len(url.QueryEscape(r.Query)) > maxEncodedQuery {
+				return false
+			}
+		}
+		return true
+	}
+	var out []contentSearch
+	var chunk []string
+	for _, t := range terms {
+		if len(chunk) > 0 && (len(chunk) == c.chunk || !fits(append(chunk[:len(chunk):len(chunk)], t))) {
+			out = append(out, search(chunk))
+			chunk = nil
+		}
+		chunk = append(chunk, t)
+	}
+	if len(chunk) > 0 {
+		out = append(out, search(chunk))
+	}
+	return out
+}
+
+func (c *Config) contentsAttack(ctx context.Context) {
+	c.announce()
+	if fileExists(c.contents) {
+		lines := c.contentChunks(c.allTerms(c.readTermFile(c.contents)))
+		// One dorkRun per line (or -chunk of lines) on its own Config
+		// copy, -workers at a time. Results already emitted for another
+		// term are skipped, and reporting/output is serialized so terms
+		// don't interleave.
+		workers := c.workers
+		if workers < 1 {
+			workers = 1
+		}
+		seen := NewSafeSet(c.output().compact)
+		var outMu sync.Mutex
+		jobs := make(chan contentSearch)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for job := range jobs {
+					c2 := *c
+					c2.contents, c2.inFile = job.term, job.query
+					// results another term found already are left out
+					emit := func(res []result) {
+						fresh := make([]result, 0, len(res))
+						for _, r := range res {
+							if seen.Add(c.resultKey(r)) {
+								fresh = append(fresh, r)
+							}
+						}
+						outMu.Lock()
+						defer outMu.Unlock()
+						logv(c2.verbose, "Files found containing: %s", job.term)
+						if len(fresh) > 0 {
+							c2.emit(ctx, fresh)
+						}
+					}
+					if res := c2.urlRun(ctx, "", emit); len(res) > 0 {
+						emit(res)
+					}
+				}
+			}()
+		}
+	feed:
+		for _, content := range lines {
+			select {
+			case jobs <- content:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		return
+	}
+	// Single value path
+	c.inFile = c.buildContentsQuery()
+	emit := func(res []result) { c.emit(ctx, res) }
+	if res := c.urlRun(ctx, "", emit); len(res) > 0 {
+		emit(res)
+	}
+}
+
+// --- Concurrency-safe unique writer (parallelization for later) ---
+type SafeSet struct {
+	mu sync.Mutex
+	m  map[string]struct{}
+	fp fpSet // used instead of m when compact
+}
+
+// NewSafeSet returns an empty set, keeping fingerprints of its values when
+// compact (-flush-every and -fast-dedupe).
+func NewSafeSet(compact bool) *SafeSet {
+	s := &SafeSet{fp: fpSet{}}
+	if !compact {
+		s.m = make(map[string]struct{})
+	}
+	return s
+}
+
+func (s *SafeSet) Add(v string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		return s.fp.add(v)
+	}
+	if _, ok := s.m[v]; ok {
+		return false
+	}
+	s.m[v] = struct{}{}
+	return true
+}
@@ -0,0 +1,693 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// writeFile writes content to name in a test's temporary directory and
+// returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// parseFlags returns a Config holding the defaults of every flag, with
+// args applied, as main would see it before prepare.
+func parseFlags(t *testing.T, args ...string) *Config {
+	t.Helper()
+	cfg := &Config{stats: &engineStats{}}
+	fs := flag.NewFlagSet("banshee", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerFlags(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// captureInfo sends the informational output of the test to a buffer.
+func captureInfo(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var b bytes.Buffer
+	old := infoOut
+	infoOut = &b
+	t.Cleanup(func() { infoOut = old })
+	return &b
+}
+
+// useOutput points the results at a fresh -o file for the test and returns
+// a function reading it back, flushed.
+func useOutput(t *testing.T, cfg *Config) func() []string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "out.txt")
+	cfg.outputPath = path
+	t.Cleanup(closeOutputs)
+	return func() []string {
+		flushOutput(path)
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bytes.TrimSpace(b)) == 0 {
+			return nil
+		}
+		return strings.Split(strings.TrimSpace(string(b)), "\n")
+	}
+}
+
+// fakeProvider is a search engine in memory: results answers each query,
+// by default with two links per query on every page. It records what was
+// asked and is safe for concurrent use.
+type fakeProvider struct {
+	name    string
+	keys    int
+	results func(q banshee.Query) ([]string, error)
+	hang    string // queries containing it go unanswered until cancelled
+
+	mu      sync.Mutex
+	queries []banshee.Query
+	usable  int
+}
+
+func newFakeProvider(results func(q banshee.Query) ([]string, error)) *fakeProvider {
+	if results == nil {
+		results = func(q banshee.Query) ([]string, error) {
+			return fakeLinks(q, 2), nil
+		}
+	}
+	return &fakeProvider{name: "google", keys: 1, usable: 1, results: results}
+}
+
+// fakeLinks returns n links on example.com unique to q's text and page.
+func fakeLinks(q banshee.Query, n int) []string {
+	slug := strings.NewReplacer(" ", "_", ":", "-", "*", "x", `"`, "").Replace(q.Text)
+	links := make([]string, n)
+	for i := range links {
+		links[i] = fmt.Sprintf("https://example.com/%s/%d", slug, q.Start+i)
+	}
+	return links
+}
+
+func (p *fakeProvider) Name() string            { return p.name }
+func (p *fakeProvider) Rewrite(q string) string { return q }
+
+func (p *fakeProvider) Search(ctx context.Context, q banshee.Query) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.queries = append(p.queries, q)
+	p.mu.Unlock()
+	if p.hang != "" && strings.Contains(q.Text, p.hang) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return p.results(q)
+}
+
+// exhaust retires every key of p.
+func (p *fakeProvider) exhaust() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.usable = 0
+}
+
+func (p *fakeProvider) QuotaState() banshee.QuotaState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return banshee.QuotaState{Requests: len(p.queries), Usable: p.usable, Keys: p.keys}
+}
+
+// sent returns the queries received, sorted.
+func (p *fakeProvider) sent() []banshee.Query {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	q := append([]banshee.Query(nil), p.queries...)
+	sort.Slice(q, func(i, j int) bool {
+		if q[i].Text != q[j].Text {
+			return q[i].Text < q[j].Text
+		}
+		return q[i].Start < q[j].Start
+	})
+	return q
+}
+
+// searchConfig returns a prepared Config searching p with args, with the
+// adaptive delay held at a microsecond, and resets the run's counters when
+// the test ends.
+func searchConfig(t *testing.T, p banshee.Provider, args ...string) *Config {
+	t.Helper()
+	cfg := parseFlags(t, append([]string{"-min-delay", "0", "-max-delay", "0.000001"}, args...)...)
+	cfg.provider = p
+	cfg.engine = p.Name()
+	cfg.engines = []string{cfg.engine}
+	cfg.providers = map[string]banshee.Provider{cfg.engine: p}
+	if err := cfg.prepare(); err != nil {
+		t.Fatal(err)
+	}
+	cfg.track = &targetTracker{}
+	t.Cleanup(func() {
+		found.Store(0)
+		saved.Store(0)
+		keysRanOut.Store(false)
+	})
+	return cfg
+}
+
+func TestExclusions(t *testing.T) {
+	mixed := writeFile(t, "exclusions.txt", "# noise\ndev.example.com\n/blog/\n*.staging.example.com\n/careers/ # jobs\n")
+	tests := []struct {
+		name      string
+		x         string
+		query     string
+		paths     []string
+		dropHosts []string
+	}{
+		{"hosts", "dev.example.com,shop.example.com", "-site:dev.example.com -site:shop.example.com", nil, nil},
+		{"paths", "/blog/,/press/", `-inurl:"/blog/" -inurl:"/press/"`, []string{"/blog/", "/press/"}, nil},
+		{"mixed list", "dev.example.com,/blog/", `-site:dev.example.com -inurl:"/blog/"`, []string{"/blog/"}, nil},
+		{"mixed file", mixed, `-site:dev.example.com -site:staging.example.com -inurl:"/blog/" -inurl:"/careers/"`,
+			[]string{"/blog/", "/careers/"}, []string{"staging.example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parseFlags(t, "-x", tt.x)
+			if err := cfg.prepare(); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.excludeTargets != tt.query {
+				t.Errorf("query exclusions = %q, want %q", cfg.excludeTargets, tt.query)
+			}
+			if !slices.Equal(cfg.excludePaths, tt.paths) {
+				t.Errorf("client-side paths = %q, want %q", cfg.excludePaths, tt.paths)
+			}
+			if !slices.Equal(cfg.excludeHosts, tt.dropHosts) {
+				t.Errorf("client-side hosts = %q, want %q", cfg.excludeHosts, tt.dropHosts)
+			}
+		})
+	}
+}
+
+// Google doesn't always honour -inurl:, so excluded paths are dropped from
+// the results as well.
+func TestExcludedPathsFiltered(t *testing.T) {
+	cfg := parseFlags(t, "-u", "example.com", "-x", "dev.example.com,/blog/")
+	if err := cfg.prepare(); err != nil {
+		t.Fatal(err)
+	}
+	got := cfg.scopeLinks([]string{
+		"https://example.com/blog/2024/post",
+		"https://example.com/BLOG/old",
+		"https://example.com/login?next=/blog/",
+		"https://example.com/admin",
+	}, false)
+	want := []string{"https://example.com/login?next=/blog/", "https://example.com/admin"}
+	if !slices.Equal(got, want) {
+		t.Errorf("scopeLinks = %q, want %q", got, want)
+	}
+}
+
+// In a mixed -x file a plain host only goes into the query, meaning that
+// host, while a *. entry also drops the results anywhere below it.
+func TestExcludedHostsFiltered(t *testing.T) {
+	x := writeFile(t, "exclusions.txt", "dev.example.com\n*.staging.example.com\n/blog/\n")
+	cfg := parseFlags(t, "-u", "example.com", "-x", x)
+	if err := cfg.prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `-site:dev.example.com -site:staging.example.com -inurl:"/blog/"`; cfg.excludeTargets != want {
+		t.Errorf("query exclusions = %q, want %q", cfg.excludeTargets, want)
+	}
+	got := cfg.scopeLinks([]string{
+		"https://api.dev.example.com/v1",
+		"https://staging.example.com/",
+		"https://a.b.staging.example.com/login",
+		"https://mystaging.example.com/",
+		"https://example.com/blog/post",
+		"https://example.com/admin",
+	}, false)
+	want := []string{"https://api.dev.example.com/v1", "https://mystaging.example.com/", "https://example.com/admin"}
+	if !slices.Equal(got, want) {
+		t.Errorf("scopeLinks = %q, want %q", got, want)
+	}
+}
+
+// Extensions searched by several workers, each sending its page's queries
+// in parallel, share the key pool, the target's tracker, the engine stats
+// and the output. Run with -race.
+func TestConcurrentExtensionSearch(t *testing.T) {
+	p := newFakeProvider(func(q banshee.Query) ([]string, error) {
+		if q.Start > 21 {
+			return nil, nil // three pages each
+		}
+		return fakeLinks(q, 2), nil
+	})
+	exts := []string{"pdf", "doc", "xls", "txt", "csv", "log"}
+	cfg := searchConfig(t, p, "-u", "example.com", "-a", "-e", strings.Join(exts, ","), "-workers", "4", "-concurrency", "3")
+	read := useOutput(t, cfg)
+	cfg.extensionAttack(context.Background())
+
+	// -a: four scopes with filetype: and ext: each, three pages with
+	// results and a fourth that comes back empty
+	queries := len(exts) * 4 * 2
+	if got := len(p.sent()); got != queries*4 {
+		t.Errorf("sent %d requests, want %d", got, queries*4)
+	}
+	if got := len(read()); got != queries*3*2 {
+		t.Errorf("wrote %d results, want %d", got, queries*3*2)
+	}
+	if got := cfg.track.pages; got != len(exts)*4 {
+		t.Errorf("tracker counted %d pages, want %d", got, len(exts)*4)
+	}
+}
+
+// Terms of a -c file searched by several workers skip results another term
+// already found, through a set they share.
+func TestConcurrentContentsSearch(t *testing.T) {
+	p := newFakeProvider(func(q banshee.Query) ([]string, error) {
+		if q.Start > 1 {
+			return nil, nil
+		}
+		// every term finds the same page besides its own
+		return append(fakeLinks(q, 1), "https://example.com/shared"), nil
+	})
+	terms := writeFile(t, "terms.txt", "internal use only\nconfidential\ndo not distribute\npassword\nsecret key\n")
+	cfg := searchConfig(t, p, "-u", "example.com", "-c", terms, "-workers", "3")
+	read := useOutput(t, cfg)
+	cfg.contentsAttack(context.Background())
+
+	got := read()
+	if len(got) != 6 {
+		t.Errorf("wrote %d results, want 6 (one per term and the shared one): %q", len(got), got)
+	}
+}
+
+// Input lines are read up to -max-line-size, well past bufio's 64KB, and
+// a longer one fails the read naming its line.
+func TestReadLinesLong(t *testing.T) {
+	long := "inurl:" + strings.Repeat("a", 1<<20)
+	path := writeFile(t, "dorks.txt", "intitle:index\n"+long+"\nfiletype:pdf\n")
+	got, err := readLines(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[1] != long {
+		t.Errorf("read %d lines, want 3 with the 1MB one intact", len(got))
+	}
+
+	old := maxLineSize
+	maxLineSize = 512 << 10
+	t.Cleanup(func() { maxLineSize = old })
+	path = writeFile(t, "dorks.txt", "intitle:index\nfiletype:pdf\n"+long+"\n")
+	_, err = readLines(path)
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Fatalf("err = %v, want bufio.ErrTooLong", err)
+	}
+	if want := path + ": line 3 is longer than 524288 bytes"; !strings.Contains(err.Error(), want) {
+		t.Errorf("err = %q, want it to say %q", err, want)
+	}
+}
+
+// -flush-every writes the results of a run in batches as the pages come
+// in: a million results, half of them repeats, reach -o deduplicated while
+// the heap stays far below what holding them would take (about 230MB for a
+// quarter of them).
+func TestFlushEveryBoundsMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("a million results")
+	}
+	p := newFakeProvider(func(q banshee.Query) ([]string, error) {
+		// each page repeats the second half of the one before
+		page := q.Start / 10
+		links := make([]string, 1000)
+		for i := range links {
+			links[i] = fmt.Sprintf("https://example.com/files/%07d", page*500+i)
+		}
+		return links, nil
+	})
+	cfg := searchConfig(t, p, "-u", "example.com", "-q", "inurl:files", "-p", "1000", "-flush-every", "10000")
+	read := useOutput(t, cfg)
+
+	var peak uint64
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		var ms runtime.MemStats
+		for {
+			runtime.ReadMemStats(&ms)
+			peak = max(peak, ms.HeapAlloc)
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+	cfg.runTarget(context.Background())
+	close(done)
+	<-sampled
+
+	if got := len(p.sent()); got != 1000 {
+		t.Fatalf("searched %d pages, want 1000", got)
+	}
+	lines := read()
+	if len(lines) != 500500 {
+		t.Errorf("-o holds %d lines, want 500500", len(lines))
+	}
+	if n := len(uniqueStrings(lines)); n != len(lines) {
+		t.Errorf("-o holds %d repeated lines", len(lines)-n)
+	}
+	if peak > 128<<20 {
+		t.Errorf("heap peaked at %dMB, want under 128MB", peak>>20)
+	}
+}
+
+// Subdomain mode writes hosts, never the URLs they came from, whatever
+// -flush-every says: it needs every result of the run.
+func TestFlushEverySubdomains(t *testing.T) {
+	p := newFakeProvider(func(q banshee.Query) ([]string, error) {
+		if q.Start > 21 {
+			return nil, nil
+		}
+		var links []string
+		for i := range 4 {
+			links = append(links, fmt.Sprintf("https://h%d.example.com/page/%d", (q.Start/10+i)%5, q.Start))
+		}
+		return links, nil
+	})
+	cfg := searchConfig(t, p, "-u", "example.com", "-s", "-flush-every", "2")
+	read := useOutput(t, cfg)
+	cfg.subdomainAttack(context.Background())
+	want := []string{"h0.example.com", "h1.example.com", "h2.example.com", "h3.example.com", "h4.example.com"}
+	if got := read(); !slices.Equal(got, want) {
+		t.Errorf("-o holds %q, want %q", got, want)
+	}
+}
+
+// exitCode runs f, which is to end the run, and returns the exit code.
+func exitCode(t *testing.T, f func()) (code int) {
+	t.Helper()
+	type exited int
+	old := osExit
+	osExit = func(code int) { panic(exited(code)) }
+	defer func() {
+		osExit = old
+		r := recover()
+		c, ok := r.(exited)
+		if !ok {
+			panic(r)
+		}
+		code = int(c)
+	}()
+	f()
+	t.Fatal("the run didn't exit")
+	return 0
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets string
+		flags   []string
+		setup   func(p *fakeProvider, cancel context.CancelFunc)
+		maxRun  time.Duration // a -max-runtime for the run
+		want    int
+		skipped []string // targets -skipped-file is to hold, timed out or never finished
+	}{
+		{name: "results", want: exitOK},
+		{name: "no results", setup: func(p *fakeProvider, _ context.CancelFunc) {
+			p.results = func(banshee.Query) ([]string, error) { return nil, nil }
+		}, want: exitNoResults},
+		{name: "keys exhausted", setup: func(p *fakeProvider, _ context.CancelFunc) {
+			p.results = func(banshee.Query) ([]string, error) {
+				p.exhaust()
+				return nil, banshee.ErrKeyExhausted
+			}
+		}, want: exitKeysExhausted},
+		{name: "domain timeout", targets: "slow.example.com\nexample.org\n", flags: []string{"-domain-timeout", "50ms"},
+			setup: func(p *fakeProvider, _ context.CancelFunc) { p.hang = "slow.example.com" },
+			want:  exitOK, skipped: []string{"slow.example.com"}},
+		{name: "domain timeout, nothing found", targets: "slow.example.com\n", flags: []string{"-domain-timeout", "50ms"},
+			setup: func(p *fakeProvider, _ context.CancelFunc) { p.hang = "slow.example.com" },
+			want:  exitNoResults, skipped: []string{"slow.example.com"}},
+		{name: "interrupted", targets: "example.com\nexample.org\n", setup: func(p *fakeProvider, cancel context.CancelFunc) {
+			// Ctrl+C while the first target's second page is searched
+			p.results = func(q banshee.Query) ([]string, error) {
+				if q.Start > 1 {
+					cancel()
+				}
+				return fakeLinks(q, 2), nil
+			}
+		}, want: exitInterrupted, skipped: []string{"example.com", "example.org"}},
+		{name: "max runtime", maxRun: 50 * time.Millisecond,
+			setup: func(p *fakeProvider, _ context.CancelFunc) { p.hang = "site:" },
+			want:  exitTimeout, skipped: []string{"example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets := tt.targets
+			if targets == "" {
+				targets = "example.com\n"
+			}
+			skipped := filepath.Join(t.TempDir(), "skipped.txt")
+			p := newFakeProvider(nil)
+			args := append([]string{"-f", writeFile(t, "targets.txt", targets), "-q", "inurl:admin", "-p", "2",
+				"-silent", "-skipped-file", skipped}, tt.flags...)
+			cfg := searchConfig(t, p, args...)
+			useOutput(t, cfg)
+			captureInfo(t)
+			ctx := context.Background()
+			if tt.maxRun > 0 {
+				var stop context.CancelFunc
+				ctx, stop = context.WithTimeout(ctx, tt.maxRun)
+				defer stop()
+			}
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			if tt.setup != nil {
+				tt.setup(p, cancel)
+			}
+			if got := exitCode(t, func() { cfg.runDomainsFile(ctx) }); got != tt.want {
+				t.Errorf("exit code %d, want %d", got, tt.want)
+			}
+			b, _ := os.ReadFile(skipped)
+			if got := strings.Fields(string(b)); !slices.Equal(got, tt.skipped) {
+				t.Errorf("-skipped-file holds %q, want %q", got, tt.skipped)
+			}
+		})
+	}
+}
+
+// An output file that can't be written fails the run, whatever it found.
+func TestExitOutputFailure(t *testing.T) {
+	cfg := searchConfig(t, newFakeProvider(nil), "-f", writeFile(t, "targets.txt", "example.com\n"), "-q", "inurl:admin", "-silent")
+	useOutput(t, cfg)
+	cfg.outputPath = filepath.Join(t.TempDir(), "missing", "out.txt")
+	t.Cleanup(func() { outputErr.Store(nil) })
+	if got := exitCode(t, func() { cfg.runDomainsFile(context.Background()) }); got != exitFatal {
+		t.Errorf("exit code %d, want %d", got, exitFatal)
+	}
+}
+
+// A search waits once between consecutive attempts, whether the next one
+// is another page or a retry of the same one, and never after the last.
+// The queries of one page go out together.
+func TestDelayBetweenAttempts(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+		pages int // pages with results
+		fail  int // first attempts at page 1 that fail
+		want  string
+	}{
+		{"every page found something", []string{"-p", "3"}, 3, 0, "1 z 11 z 21"},
+		{"the last page is empty", []string{"-p", "5"}, 2, 0, "1 z 11 z 21"},
+		{"nothing found", []string{"-p", "5"}, 0, 0, "1"},
+		{"retry after an error", []string{"-p", "2"}, 2, 1, "1 z 1 z 11"},
+		{"four queries a page", []string{"-p", "2", "-a"}, 2, 0, "1 1 1 1 z 11 11 11 11"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var events []string
+			var delays []time.Duration
+			old := sleep
+			sleep = func(d time.Duration) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, "z")
+				delays = append(delays, d)
+			}
+			t.Cleanup(func() { sleep = old })
+			fails := tt.fail
+			p := newFakeProvider(func(q banshee.Query) ([]string, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, strconv.Itoa(q.Start))
+				if fails > 0 {
+					fails--
+					return nil, banshee.ErrRateLimited
+				}
+				if q.Start > (tt.pages-1)*10+1 {
+					return nil, nil
+				}
+				return fakeLinks(q, 2), nil
+			})
+			p.keys, p.usable = 2, 2
+			cfg := searchConfig(t, p, append([]string{"-u", "example.com", "-q", "inurl:admin", "-d", "2"}, tt.flags...)...)
+			cfg.dorkRun(context.Background(), "")
+			if got := strings.Join(events, " "); got != tt.want {
+				t.Errorf("requests and delays = %q, want %q", got, tt.want)
+			}
+			for _, d := range delays {
+				if d != 2*time.Second {
+					t.Errorf("slept %v, want -d's 2s", d)
+				}
+			}
+		})
+	}
+}
+
+// Files saved by Windows tools, with a byte order mark and CRLF line ends,
+// read like any others: the first key doesn't carry the mark and no term
+// ends up in a query with a \r.
+func TestWindowsInputFiles(t *testing.T) {
+	keys, err := readApiKeysFromFile("testdata/keys-crlf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"AIzaKeyOne", "AIzaKeyTwo"}; !slices.Equal(keys, want) {
+		t.Errorf("keys = %q, want %q", keys, want)
+	}
+
+	const words = "testdata/wordlist-crlf.txt"
+	p := newFakeProvider(func(q banshee.Query) ([]string, error) { return nil, nil })
+	cfg := searchConfig(t, p, "-u", "example.com", "-w", words)
+	if got, want := cfg.readTermFile(words), []string{"admin", "login", "wp-admin"}; !slices.Equal(got, want) {
+		t.Errorf("terms = %q, want %q", got, want)
+	}
+	cfg.dictionaryAttack(context.Background())
+	sent := p.sent()
+	if len(sent) == 0 {
+		t.Fatal("no queries sent")
+	}
+	for _, q := range sent {
+		if strings.ContainsAny(q.Text, "\r"+utf8BOM) {
+			t.Errorf("query %q carries a \\r or byte order mark", q.Text)
+		}
+	}
+	if q := sent[0].Text; !strings.Contains(q, `inurl:"admin"`) {
+		t.Errorf("query %q, want it to hold inurl:\"admin\"", q)
+	}
+}
+
+// Results of the pages searched before an interruption are written, in
+// every mode: a run cancelled while asking for page 3 keeps pages 1 and 2.
+func TestCancelledRunKeepsResults(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+	}{
+		{"dork", []string{"-q", "inurl:admin"}},
+		{"dork, flushed", []string{"-q", "inurl:admin", "-flush-every", "1"}},
+		{"dictionary", []string{"-w", "admin,login"}},
+		{"extension", []string{"-e", "pdf"}},
+		{"contents", []string{"-c", "confidential"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			p := newFakeProvider(func(q banshee.Query) ([]string, error) {
+				if q.Start >= 21 {
+					cancel()
+					return nil, context.Canceled
+				}
+				return fakeLinks(q, 2), nil
+			})
+			cfg := searchConfig(t, p, append([]string{"-u", "example.com", "-p", "5"}, tt.flags...)...)
+			read := useOutput(t, cfg)
+			cfg.runTarget(ctx)
+
+			var want []string
+			for _, q := range p.sent() {
+				if q.Start < 21 {
+					want = append(want, fakeLinks(q, 2)...)
+				}
+			}
+			got := read()
+			slices.Sort(got)
+			slices.Sort(want)
+			if len(want) == 0 || !slices.Equal(got, want) {
+				t.Errorf("-o holds %q, want the results of pages 1-2 %q", got, want)
+			}
+		})
+	}
+}
+
+// -c-mode any matches any word of each -c value instead of the phrase,
+// whether the values are listed on the command line or read from a file.
+func TestContentsMode(t *testing.T) {
+	file := writeFile(t, "contents.txt", "internal use only\npassword\n")
+	tests := []struct {
+		name, c, mode string
+		query         string   // buildContentsQuery
+		sent          []string // the -c part of the queries contentsAttack sends
+	}{
+		{"list, phrase", "internal use only,password", "phrase",
+			`intext:"internal use only" OR intext:"password"`,
+			[]string{`intext:"internal use only" OR intext:"password"`}},
+		{"list, any", "internal use only,password", "any",
+			`(intext:"internal" OR intext:"use" OR intext:"only") OR intext:"password"`,
+			[]string{`(intext:"internal" OR intext:"use" OR intext:"only") OR intext:"password"`}},
+		{"file, phrase", file, "phrase",
+			`intext:"internal use only"`,
+			[]string{`intext:"internal use only"`, `intext:"password"`}},
+		{"file, any", file, "any",
+			`(intext:"internal" OR intext:"use" OR intext:"only")`,
+			[]string{`(intext:"internal" OR intext:"use" OR intext:"only")`, `intext:"password"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakeProvider(func(q banshee.Query) ([]string, error) { return nil, nil })
+			cfg := searchConfig(t, p, "-u", "example.com", "-c", tt.c, "-c-mode", tt.mode)
+			if got := cfg.buildContentsQuery(); got != tt.query {
+				t.Errorf("buildContentsQuery = %q, want %q", got, tt.query)
+			}
+			cfg.contentsAttack(context.Background())
+			var got, want []string
+			for _, q := range p.sent() {
+				got = append(got, q.Text)
+			}
+			for _, c := range tt.sent {
+				want = append(want, "site:example.com "+c)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("sent %q, want %q", got, want)
+			}
+		})
+	}
+}
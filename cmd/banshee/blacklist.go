@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// --- Result blacklist (-bl) ---
+
+// blacklist holds the -bl entries: exact URLs, "prefix*" entries and
+// "re:<regex>" entries. Exact and prefix lookups are map based, so matching
+// cost depends on the URL length rather than on the number of entries.
+type blacklist struct {
+	exact    map[string]struct{}
+	prefixes map[string]struct{}
+	maxPref  int
+	re       *regexp.Regexp
+}
+
+func loadBlacklist(path string) (*blacklist, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	bl := &blacklist{exact: map[string]struct{}{}, prefixes: map[string]struct{}{}}
+	var res []string
+	for i, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "re:"):
+			expr := strings.TrimPrefix(l, "re:")
+			if _, err := regexp.Compile(expr); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+			}
+			res = append(res, "(?:"+expr+")")
+		case strings.HasSuffix(l, "*"):
+			p := strings.TrimSuffix(l, "*")
+			bl.prefixes[p] = struct{}{}
+			if len(p) > bl.maxPref {
+				bl.maxPref = len(p)
+			}
+		default:
+			bl.exact[l] = struct{}{}
+		}
+	}
+	if len(res) > 0 {
+		bl.re = regexp.MustCompile(strings.Join(res, "|"))
+	}
+	return bl, nil
+}
+
+func (bl *blacklist) match(u string) bool {
+	if _, ok := bl.exact[u]; ok {
+		return true
+	}
+	if len(bl.prefixes) > 0 {
+		n := len(u)
+		if n > bl.maxPref {
+			n = bl.maxPref
+		}
+		for i := 0; i <= n; i++ {
+			if _, ok := bl.prefixes[u[:i]]; ok {
+				return true
+			}
+		}
+	}
+	return bl.re != nil && bl.re.MatchString(u)
+}
+
+// applyBlacklist drops blacklisted results and reports how many were
+// suppressed in verbose mode.
+func (c *Config) applyBlacklist(res []result) []result {
+	if c.bl == nil {
+		return res
+	}
+	out := res[:0]
+	dropped := 0
+	for _, r := range res {
+		if c.bl.match(r.url) {
+			dropped++
+			continue
+		}
+		out = append(out, r)
+	}
+	if dropped > 0 {
+		logv(c.verbose, "Blacklist suppressed %d result(s)", dropped)
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// --- Extension bundles ---
+
+// ext-bundles.json maps a bundle name to its extensions; -e @name stands
+// for them.
+//
+//go:embed ext-bundles.json
+var builtinExtBundles []byte
+
+func extBundles() map[string][]string {
+	b := map[string][]string{}
+	if err := json.Unmarshal(builtinExtBundles, &b); err != nil {
+		panic("ext-bundles.json: " + err.Error())
+	}
+	return b
+}
+
+// expandExtensions replaces the @bundle entries of a comma-separated -e
+// value with their extensions, keeping the first of any repeats. A value
+// without bundles is returned as is.
+func expandExtensions(v string) (string, error) {
+	if !strings.Contains(v, "@") {
+		return v, nil
+	}
+	bundles := extBundles()
+	var out []string
+	seen := map[string]bool{}
+	for _, e := range strings.Split(v, ",") {
+		e = strings.TrimSpace(e)
+		exts := []string{e}
+		if name, ok := strings.CutPrefix(e, "@"); ok {
+			if exts, ok = bundles[name]; !ok {
+				return "", fmt.Errorf("unknown -e bundle %q (available: %s)", e, strings.Join(bundleNames(bundles), ", "))
+			}
+		}
+		for _, x := range exts {
+			if x != "" && !seen[x] {
+				seen[x] = true
+				out = append(out, x)
+			}
+		}
+	}
+	return strings.Join(out, ","), nil
+}
+
+func bundleNames(bundles map[string][]string) []string {
+	names := make([]string, 0, len(bundles))
+	for k := range bundles {
+		names = append(names, "@"+k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printBundles lists the bundles for -e list-bundles.
+func printBundles() {
+	bundles := extBundles()
+	for _, n := range bundleNames(bundles) {
+		fmt.Printf("%-12s %s\n", n, strings.Join(bundles[n[1:]], ","))
+	}
+}
+
+// --- Built-in wordlists ---
+
+// wordlists holds the curated -w lists, one term per line in the format
+// of a dictionary file; -w @name stands for wordlists/name.txt.
+//
+//go:embed wordlists/*.txt
+var builtinWordlists embed.FS
+
+// wordlistRef reports whether a -w value names built-in wordlists, so it
+// is read like a dictionary file rather than as a list of terms.
+func wordlistRef(v string) bool {
+	return strings.Contains(v, "@") && !fileExists(v)
+}
+
+// isTermSource reports whether a -w value is read through openTerms: a
+// file, or a list with built-in wordlists in it.
+func isTermSource(v string) bool {
+	return fileExists(v) || wordlistRef(v)
+}
+
+// openTerms opens a dictionary file, or for a comma-separated value with
+// @wordlist entries, the concatenation of those lists and the other
+// entries, one per line.
+func openTerms(v string) (io.ReadCloser, error) {
+	if !wordlistRef(v) {
+		return os.Open(v)
+	}
+	var rs []io.Reader
+	for _, e := range strings.Split(v, ",") {
+		e = strings.TrimSpace(e)
+		name, ok := strings.CutPrefix(e, "@")
+		if !ok {
+			rs = append(rs, strings.NewReader(e+"\n"))
+			continue
+		}
+		data, err := builtinWordlists.ReadFile("wordlists/" + name + ".txt")
+		if err != nil {
+			return nil, fmt.Errorf("unknown -w wordlist %q (available: %s)", e, strings.Join(wordlistNames(), ", "))
+		}
+		rs = append(rs, bytes.NewReader(data), strings.NewReader("\n"))
+	}
+	return io.NopCloser(io.MultiReader(rs...)), nil
+}
+
+func wordlistNames() []string {
+	entries, _ := builtinWordlists.ReadDir("wordlists")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, "@"+strings.TrimSuffix(e.Name(), ".txt"))
+	}
+	return names
+}
+
+// printWordlists lists the built-in wordlists and their term counts for
+// -w list.
+func printWordlists() {
+	for _, n := range wordlistNames() {
+		fmt.Printf("%-16s %d terms\n", n, countTerms(n))
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Google cache lookups for results ---
+
+const googleCacheURL = "https://webcache.googleusercontent.com/search?q=cache:"
+
+type cacheState int
+
+const (
+	cacheMissing cacheState = iota
+	cacheFound
+	cacheUnknown // consent page, captcha, network error...
+)
+
+func (s cacheState) String() string {
+	switch s {
+	case cacheFound:
+		return "[cached]"
+	case cacheMissing:
+		return "[no-cache]"
+	}
+	return "[cache-unknown]"
+}
+
+// cacheCheck looks up the Google cache copy of every line (or, with -probe,
+// of the lines whose probe failed or returned 4xx/5xx) and appends its
+// state. urls[i] is the bare URL of lines[i]; "" lines are skipped. With
+// -cache-dir, cached pages are saved there.
+func (c *Config) cacheCheck(ctx context.Context, urls, lines []string) []string {
+	workers := c.cacheWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	if c.cacheDir != "" {
+		if err := os.MkdirAll(c.cacheDir, 0o755); err != nil {
+			logErr("[!] cannot create cache directory: %v", err)
+			return lines
+		}
+	}
+	var todo []int
+	for i, l := range lines {
+		if l != "" && (!c.probe || deadProbe(l, urls[i])) {
+			todo = append(todo, i)
+		}
+	}
+	out := append([]string(nil), lines...)
+	var mu sync.Mutex // guards file naming in cacheDir
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				st := c.cacheOne(ctx, urls[i], &mu)
+				out[i] = lines[i] + " " + st.String()
+			}
+		}()
+	}
+feed:
+	for _, i := range todo {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return out
+}
+
+// deadProbe reports whether a -probe line (url followed by its annotation)
+// describes a failed fetch or an error status.
+func deadProbe(line, u string) bool {
+	rest := strings.TrimSpace(strings.TrimPrefix(line, u))
+	if strings.HasPrefix(rest, "[failed]") {
+		return true
+	}
+	var status int
+	if _, err := fmt.Sscanf(rest, "[%d]", &status); err != nil {
+		return false
+	}
+	return status >= 400
+}
+
+func (c *Config) cacheOne(ctx context.Context, u string, mu *sync.Mutex) cacheState {
+	rctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(rctx, http.MethodGet, googleCacheURL+url.QueryEscape(u), nil)
+	if err != nil {
+		return cacheUnknown
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		logv(c.verbose, "Cache lookup failed: %s (%v)", u, err)
+		return cacheUnknown
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	// Redirects are followed; ending up on a consent or "sorry" (captcha)
+	// page says nothing about the cache.
+	final := resp.Request.URL
+	if strings.HasPrefix(final.Host, "consent.") || strings.HasPrefix(final.Path, "/sorry") {
+		return cacheUnknown
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return cacheMissing
+	case resp.StatusCode != http.StatusOK:
+		return cacheUnknown
+	}
+	if c.cacheDir != "" {
+		mu.Lock()
+		p := uniquePath(filepath.Join(c.cacheDir, downloadName(u, "html")))
+		err := os.WriteFile(p, body, 0o644)
+		mu.Unlock()
+		if err != nil {
+			logErr("[!] cannot save cached copy of %s: %v", u, err)
+		}
+	}
+	return cacheFound
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Config file ---
+
+// configDirs lists the places keys and the config file are looked for, in
+// order: the platform's config directory (which honours $XDG_CONFIG_HOME on
+// Linux and is under %AppData% on Windows), then ~/.config/banshee, where
+// older versions kept them.
+func configDirs() []string {
+	var dirs []string
+	if d, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(d, "banshee"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if legacy := filepath.Join(home, ".config", "banshee"); len(dirs) == 0 || dirs[0] != legacy {
+			dirs = append(dirs, legacy)
+		}
+	}
+	return dirs
+}
+
+// configDir is where keys and the config file live: the first of
+// configDirs that exists, or the first one when none does yet.
+func configDir() (string, error) {
+	dirs := configDirs()
+	if len(dirs) == 0 {
+		return "", errors.New("cannot locate a config directory: neither a user config nor a home directory is known")
+	}
+	for _, d := range dirs {
+		if fi, err := os.Stat(d); err == nil && fi.IsDir() {
+			return d, nil
+		}
+	}
+	return dirs[0], nil
+}
+
+// keyFileEnv names a key file that is used when -k isn't given.
+const keyFileEnv = "BANSHEE_KEYS"
+
+// loadAPIKeys reads the keys of engine. explicit, the -k value, applies to
+// the main engine only; the others, and the main engine without -k, use
+// the file named by $BANSHEE_KEYS (main engine only) or <engine>-keys.txt
+// (keys.txt for Google) in each of configDirs. The first file that has
+// keys wins and its path is returned; when none has, the error lists
+// every path tried.
+func loadAPIKeys(engine, explicit string, main bool) (keys []string, path string, err error) {
+	type source struct{ what, path string }
+	var tried []source
+	if main {
+		if explicit != "" {
+			tried = append(tried, source{"-k", explicit})
+		}
+		if env := os.Getenv(keyFileEnv); env != "" {
+			tried = append(tried, source{"$" + keyFileEnv, env})
+		}
+	}
+	name := "keys.txt"
+	if engine != "google" {
+		name = engine + "-keys.txt"
+	}
+	for _, d := range configDirs() {
+		tried = append(tried, source{"", filepath.Join(d, name)})
+	}
+	var fails []string
+	overridden := false // -k or $BANSHEE_KEYS was given but unusable
+	for _, s := range tried {
+		keys, err := readApiKeysFromFile(s.path)
+		if err == nil {
+			if overridden {
+				logErr("[!] %s; using %s instead", strings.Join(fails, "; "), s.path)
+			}
+			return keys, s.path, nil
+		}
+		msg := err.Error()
+		if errors.Is(err, os.ErrNotExist) {
+			msg = "not found"
+		}
+		if s.what != "" {
+			overridden = true
+			fails = append(fails, fmt.Sprintf("%s %s: %s", s.what, s.path, msg))
+		} else {
+			fails = append(fails, fmt.Sprintf("%s: %s", s.path, msg))
+		}
+	}
+	return nil, "", fmt.Errorf("no %s keys found (tried %s)", engine, strings.Join(fails, "; "))
+}
+
+// configFileName is the config file in configDir: one "flag = value" line
+// per default, # comments allowed. Flags given on the command line win.
+const configFileName = "config"
+
+// configLoaded is the config file applied to this run, "" if none.
+var configLoaded string
+
+// applyConfigFile sets the flags of fs listed in the config file, if there
+// is one. It runs before fs.Parse so the command line overrides it.
+func applyConfigFile(fs *flag.FlagSet) error {
+	dir, err := configDir()
+	if err != nil {
+		return nil
+	}
+	path := filepath.Join(dir, configFileName)
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for i, l := range strings.Split(strings.TrimPrefix(string(b), utf8BOM), "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(l, "=")
+		name = strings.TrimLeft(strings.TrimSpace(name), "-")
+		value = strings.TrimSpace(value)
+		f := fs.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("%s:%d: unknown flag %q", path, i+1, name)
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() && !hasValue {
+			value = "true" // a bare "silent" line, like -silent
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s:%d: %s: %v", path, i+1, name, err)
+		}
+	}
+	configLoaded = path
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// --- Count-only output (-count) ---
+
+// targetCount holds the unique results of one target under -count, which
+// reports how many there are instead of writing them.
+type targetCount struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+func newTargetCount() *targetCount {
+	return &targetCount{keys: map[string]struct{}{}}
+}
+
+func (t *targetCount) add(key string) {
+	t.mu.Lock()
+	t.keys[key] = struct{}{}
+	t.mu.Unlock()
+}
+
+func (t *targetCount) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.keys)
+}
+
+// counted adds up the -count totals, so a run whose targets all have none
+// still exits with exitNoResults.
+var counted atomic.Int64
+
+// writeCount writes target<TAB>count for c.target to -o or stdout.
+func (c *Config) writeCount() {
+	if c.count == nil {
+		return
+	}
+	n := c.count.len()
+	counted.Add(int64(n))
+	c.output().writeUnique([]string{c.target + "\t" + strconv.Itoa(n)}, c.outputPath, exactKey)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// crt.sh (-sources crtsh) adds certificate transparency names to subdomain
+// mode: CT logs list every host that ever got a certificate, not just the
+// ones Google indexed.
+
+const crtshURL = "https://crt.sh/"
+
+type crtshEntry struct {
+	NameValue string `json:"name_value"`
+}
+
+// crtshHosts returns the names in certificates issued for domain and its
+// subdomains, lowercased and with wildcard labels removed. Names are not
+// scoped here; callers apply inScope like for Google results.
+func (c *Config) crtshHosts(ctx context.Context, domain string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	u := crtshURL + "?q=" + url.QueryEscape("%."+domain) + "&output=json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	// crt.sh is slow for big domains; rely on ctx instead of the API timeout
+	cl := *c.client
+	cl.Timeout = 0
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("crt.sh: status %d", resp.StatusCode)
+	}
+	// Responses for large domains run to many megabytes; decode the array
+	// entry by entry.
+	dec := json.NewDecoder(resp.Body)
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("crt.sh: %w", err)
+	}
+	seen := map[string]struct{}{}
+	var hosts []string
+	for dec.More() {
+		var e crtshEntry
+		if err := dec.Decode(&e); err != nil {
+			return hosts, fmt.Errorf("crt.sh: %w", err)
+		}
+		// name_value holds one SAN per line
+		for _, n := range strings.Split(e.NameValue, "\n") {
+			n = strings.TrimSpace(strings.ToLower(n))
+			for strings.HasPrefix(n, "*.") {
+				n = n[2:]
+			}
+			n = banshee.ASCIIHost(strings.TrimSuffix(n, "."))
+			if n == "" || strings.ContainsAny(n, "*@ ") {
+				continue
+			}
+			if _, ok := seen[n]; !ok {
+				seen[n] = struct{}{}
+				hosts = append(hosts, n)
+			}
+		}
+	}
+	return hosts, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"slices"
+	"sync"
+)
+
+// --- Diff against a baseline (-diff) ---
+
+// diffState collects the run's results under -diff instead of writing them,
+// so they can be compared with the baseline once the run is complete.
+// Results are kept apart by the key they were written under.
+type diffState struct {
+	mu          sync.Mutex
+	out         outputOptions
+	baseline    string
+	missingPath string
+	outputPath  string              // results path being intercepted ("" for stdout)
+	keys        []keyFunc           // in the order first used
+	seen        map[string]bool     // key name, NUL, key
+	lines       map[string][]string // by key name
+}
+
+var diffRun *diffState
+
+func (d *diffState) add(lines []string, key keyFunc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.lines[key.name]; !ok {
+		d.keys = append(d.keys, key)
+		d.lines[key.name] = nil
+	}
+	for _, l := range lines {
+		k := key.name + "\x00" + key.of(l)
+		if !d.seen[k] {
+			d.seen[k] = true
+			d.lines[key.name] = append(d.lines[key.name], l)
+		}
+	}
+}
+
+// flushDiff writes the results missing from the baseline as usual and,
+// when the run completed, reports baseline entries it didn't find again.
+// An interrupted run says nothing about what is missing.
+func flushDiff(complete bool) {
+	d := diffRun
+	if d == nil {
+		return
+	}
+	diffRun = nil
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	base := readListFile(d.baseline)
+	var added, unchanged int
+	for _, key := range d.keys {
+		inBase := make(map[string]bool, len(base))
+		for _, l := range base {
+			inBase[key.of(l)] = true
+		}
+		var fresh []string
+		for _, l := range d.lines[key.name] {
+			if !inBase[key.of(l)] {
+				fresh = append(fresh, l)
+			}
+		}
+		d.out.writeUnique(fresh, d.outputPath, key)
+		added += len(fresh)
+		unchanged += len(d.lines[key.name]) - len(fresh)
+	}
+	if !complete {
+		logInfo("[*] diff: %d new, %d unchanged (run incomplete, missing not computed)", added, unchanged)
+		return
+	}
+	var missing []string
+	for _, l := range base {
+		if !slices.ContainsFunc(d.keys, func(k keyFunc) bool { return d.seen[k.name+"\x00"+k.of(l)] }) {
+			missing = append(missing, l)
+		}
+	}
+	if d.missingPath != "" {
+		d.out.writeUnique(missing, d.missingPath, exactKey)
+	} else {
+		for _, l := range missing {
+			logInfo("[-] %s", l)
+		}
+	}
+	logInfo("[*] diff: %d new, %d missing, %d unchanged", added, len(missing), unchanged)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// --- Discord notifications ---
+
+// Discord rejects embeds whose description exceeds 4096 characters, and
+// caps the whole message at 6000; the sample is kept well inside both.
+const discordSampleLimit = 1800
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields"`
+	Footer      struct {
+		Text string `json:"text"`
+	} `json:"footer"`
+}
+
+// notifyDiscord posts s to the -discord-webhook as an embed.
+func (c *Config) notifyDiscord(s runSummary) error {
+	mode := s.Mode
+	if mode == "" {
+		mode = "search"
+	}
+	e := discordEmbed{
+		Title: "banshee: " + s.Target,
+		Color: 0x2ecc71,
+		Fields: []discordField{
+			{Name: "Mode", Value: mode, Inline: true},
+			{Name: "New results", Value: strconv.Itoa(s.New), Inline: true},
+			{Name: "Elapsed", Value: s.Elapsed, Inline: true},
+		},
+	}
+	if !s.Complete || s.Errors > 0 {
+		e.Color = 0xe67e22
+		e.Fields = append(e.Fields, discordField{Name: "Errors", Value: strconv.FormatInt(s.Errors, 10), Inline: true})
+	}
+	if !s.Complete {
+		e.Title += " (stopped early)"
+	}
+	if len(s.Sample) > 0 {
+		e.Description = "```\n" + truncateSample(s.Sample, discordSampleLimit) + "\n```"
+	}
+	e.Footer.Text = "run " + s.RunID + " · banshee " + s.Version
+	body, err := json.Marshal(map[string]any{"embeds": []discordEmbed{e}})
+	if err != nil {
+		return err
+	}
+	return c.postWithRetry(c.discordWebhook, body, map[string]string{"Content-Type": "application/json"})
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Downloading files found in extension mode ---
+
+type downloadJob struct {
+	url string
+	ext string
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// downloadFiles fetches each job into c.downloadDir with a bounded worker
+// pool. Failures are logged and never abort the run.
+func (c *Config) downloadFiles(ctx context.Context, jobs []downloadJob) {
+	if err := os.MkdirAll(c.downloadDir, 0o755); err != nil {
+		logErr("[!] cannot create download directory: %v", err)
+		return
+	}
+	workers := c.downloadWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	ch := make(chan downloadJob)
+	var wg sync.WaitGroup
+	var mu sync.Mutex // keeps filename allocation race-free
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range ch {
+				if err := c.downloadOne(ctx, j, &mu); err != nil {
+					logErr("[!] download failed: %s (%v)", j.url, err)
+				}
+			}
+		}()
+	}
+	seen := make(map[string]struct{}, len(jobs))
+feed:
+	for _, j := range jobs {
+		if _, dup := seen[j.url]; dup {
+			continue
+		}
+		seen[j.url] = struct{}{}
+		select {
+		case ch <- j:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(ch)
+	wg.Wait()
+}
+
+func (c *Config) downloadOne(ctx context.Context, j downloadJob, mu *sync.Mutex) error {
+	maxBytes := int64(c.downloadMaxMB * 1024 * 1024)
+	rctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(rctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	// The API client has a short overall timeout; downloads rely on rctx instead.
+	cl := *c.client
+	cl.Timeout = 0
+	resp, err := cl.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if contentTypeMismatch(resp.Header.Get("Content-Type"), j.ext) {
+		return fmt.Errorf("content type %q does not match .%s", resp.Header.Get("Content-Type"), j.ext)
+	}
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		return fmt.Errorf("size %d exceeds -download-max-size", resp.ContentLength)
+	}
+
+	mu.Lock()
+	dst := uniquePath(filepath.Join(c.downloadDir, downloadName(j.url, j.ext)))
+	f, err := os.OpenFile(dst+".part", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	mu.Unlock()
+	if err != nil {
+		return err
+	}
+	body := io.Reader(resp.Body)
+	if maxBytes > 0 {
+		body = io.LimitReader(resp.Body, maxBytes+1)
+	}
+	n, err := io.Copy(f, body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && maxBytes > 0 && n > maxBytes {
+		err = errors.New("exceeds -download-max-size")
+	}
+	if err != nil {
+		os.Remove(dst + ".part")
+		return err
+	}
+	if err := os.Rename(dst+".part", dst); err != nil {
+		return err
+	}
+	logv(c.verbose, "Downloaded: %s -> %s (%d bytes)", j.url, dst, n)
+	return nil
+}
+
+// contentTypeMismatch reports whether the server clearly answered with
+// something other than the requested filetype, i.e. an HTML page (login
+// wall, soft 404) for a non-HTML extension.
+func contentTypeMismatch(contentType, ext string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mt != "text/html" {
+		return false
+	}
+	switch strings.ToLower(ext) {
+	case "html", "htm", "xhtml", "php", "asp", "aspx", "jsp", "cfm", "shtml":
+		return false
+	}
+	return true
+}
+
+// downloadName builds a filesystem-safe name from the URL's host and path,
+// e.g. https://docs.example.com/a/b.pdf -> docs.example.com_a_b.pdf.
+func downloadName(raw, ext string) string {
+	name := "download"
+	if u, err := url.Parse(raw); err == nil {
+		name = strings.Trim(unsafeFileChars.ReplaceAllString(u.Hostname()+"_"+strings.Trim(u.Path, "/"), "_"), "_.")
+	}
+	if len(name) > 200 {
+		name = name[:200]
+	}
+	if ext != "" && !strings.HasSuffix(strings.ToLower(name), "."+strings.ToLower(ext)) {
+		name += "." + ext
+	}
+	return name
+}
+
+// uniquePath appends -1, -2, ... before the extension until p is unused.
+func uniquePath(p string) string {
+	if !fileExists(p) && !fileExists(p+".part") {
+		return p
+	}
+	ext := filepath.Ext(p)
+	base := strings.TrimSuffix(p, ext)
+	for i := 1; ; i++ {
+		cand := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if !fileExists(cand) && !fileExists(cand+".part") {
+			return cand
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// Multi-engine mode (-engine google,yandex): every logical query goes to
+// each engine on its own Config copy, with that engine's key pool and delay
+// state, and the results are merged through the usual dedup. An engine that
+// fails or runs out of keys only stops contributing.
+
+var knownEngines = banshee.Engines
+
+// parseEngines splits the -engine value, rejecting unknown names.
+func parseEngines(v string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	for _, e := range strings.Split(v, ",") {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" || seen[e] {
+			continue
+		}
+		known := false
+		for _, k := range knownEngines {
+			known = known || k == e
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown -engine %q (want %s)", e, strings.Join(knownEngines, ", "))
+		}
+		seen[e] = true
+		out = append(out, e)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("-engine is empty")
+	}
+	return out, nil
+}
+
+// loadProviders sets up a provider, with its own key pool, for every
+// selected engine. With several engines, one whose keys can't be loaded is
+// dropped with a warning. It needs c.client.
+func (c *Config) loadProviders() error {
+	c.providers = map[string]banshee.Provider{}
+	var kept []string
+	for i, e := range c.engines {
+		keys, path, err := loadAPIKeys(e, c.keysFile, i == 0)
+		if err != nil {
+			if len(c.engines) == 1 {
+				return err
+			}
+			logErr("[!] %s: no usable keys, engine disabled: %v", e, err)
+			continue
+		}
+		logv(c.verbose, "%s keys: %s (%d)", e, path, len(keys))
+		p, err := c.newProvider(e, keys)
+		if err != nil {
+			return err
+		}
+		c.providers[e] = p
+		kept = append(kept, e)
+	}
+	if len(kept) == 0 {
+		return errors.New("no engine has usable keys")
+	}
+	c.engines = kept
+	c.engine = kept[0]
+	c.provider = c.providers[kept[0]]
+	return nil
+}
+
+// newProvider sets up engine name with keys, logging its queries and
+// retired and readmitted keys.
+func (c *Config) newProvider(name string, keys []string) (banshee.Provider, error) {
+	lg.addSecrets(keys...)
+	verbose := c.verbose
+	return banshee.NewProvider(name, keys, banshee.ProviderOptions{
+		HTTPClient:     c.client,
+		CX:             c.cx,
+		KeyCooldown:    c.keyCooldown,
+		KeyInterval:    c.keyInterval,
+		RequestTimeout: c.requestTimeout,
+		OnEvent: func(e banshee.Event) {
+			switch e.Kind {
+			case "query":
+				logv(verbose, "Using API Key: %s", e.Key)
+				lg.event("query", "engine", e.Engine, "key", e.Key, "query", e.Query, "start", e.Start)
+			case "key_exhausted":
+				logv(verbose, "API key exhausted: %s", e.Key)
+				kv := []any{"engine", e.Engine, "key", e.Key, "error", e.Error}
+				if e.Project != "" {
+					kv = append(kv, "project", e.Project)
+				}
+				lg.event("key_exhausted", kv...)
+			case "key_invalid":
+				logErr("[!] %s API key rejected, not used again: %s (%s)", e.Engine, redactKey(e.Key), e.Error)
+				lg.event("key_invalid", "engine", e.Engine, "key", e.Key, "error", e.Error)
+			case "project_exhausted":
+				projectExhausted.Store(true)
+				logErr("[!] %s project %s quota exhausted: retired %d more keys that are likely in it; keys that answered stay in rotation",
+					e.Engine, e.Project, len(e.Retired))
+				lg.event("project_exhausted", "engine", e.Engine, "project", e.Project, "retired", len(e.Retired), "error", e.Error)
+			case "key_readmitted":
+				logv(verbose, "API key back in rotation: %s", e.Key)
+				lg.event("key_readmitted", "engine", e.Engine, "key", e.Key)
+			}
+		},
+	})
+}
+
+// multiEngine reports whether results are merged from several engines.
+func (c *Config) multiEngine() bool {
+	return len(c.providers) > 1
+}
+
+// mergeEngines runs the same search on every engine in parallel and merges
+// the results, earlier engines in -engine order winning duplicates. The
+// merged run counts as failed if any engine's did.
+func (c *Config) mergeEngines(ctx context.Context, ext string) *runState {
+	per := make([]*runState, len(c.engines))
+	var wg sync.WaitGroup
+	for i, e := range c.engines {
+		c2 := *c
+		c2.engines = nil
+		c2.engine = e
+		c2.provider = c.providers[e]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			per[i] = c2.searchPages(ctx, ext)
+		}()
+	}
+	wg.Wait()
+	merged := &runState{hits: map[string]bool{}}
+	for i, st := range per {
+		c.stats.add(c.engines[i], len(st.store)+st.flushed)
+		merged.store = append(merged.store, st.store...)
+		for t := range st.hits {
+			merged.hits[t] = true
+		}
+		merged.failed = merged.failed || st.failed
+	}
+	if len(merged.store) > 0 {
+		merged.store = c.uniqueTagged(merged.store)
+	}
+	return merged
+}
+
+// engineStats counts the results each engine returned, before merging,
+// and the requests it retried after an unreadable response.
+type engineStats struct {
+	mu       sync.Mutex
+	results  map[string]int
+	retries  map[string]int     // decode retries sent
+	gaveUp   map[string]int     // retried requests still unreadable at the end
+	requests map[[2]string]int  // engine, outcome -> API requests, for /metrics
+	delay    map[string]float64 // the last dynamic delay of each engine's searches
+}
+
+func (s *engineStats) add(engine string, n int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.results == nil {
+		s.results = map[string]int{}
+	}
+	s.results[engine] += n
+}
+
+// decodeRetried counts one retry of a request after an unreadable
+// response; gaveUp tells whether it was the last one and unreadable too.
+func (s *engineStats) decodeRetried(engine string, gaveUp bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.retries == nil {
+		s.retries, s.gaveUp = map[string]int{}, map[string]int{}
+	}
+	s.retries[engine]++
+	if gaveUp {
+		s.gaveUp[engine]++
+	}
+}
+
+// requestDone counts one API request of engine by its outcome. A search
+// that found no usable key made none.
+func (s *engineStats) requestDone(engine string, err error) {
+	if s == nil || errors.Is(err, banshee.ErrNoKeys) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.requests == nil {
+		s.requests = map[[2]string]int{}
+	}
+	s.requests[[2]string{engine, requestOutcome(err)}]++
+}
+
+// requestOutcome names the outcome of an API request for /metrics.
+func requestOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, banshee.ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, banshee.ErrKeyExhausted):
+		return "key_exhausted"
+	case errors.Is(err, banshee.ErrKeyInvalid):
+		return "key_invalid"
+	case errors.Is(err, banshee.ErrInvalidQuery):
+		return "invalid_query"
+	case errors.Is(err, banshee.ErrBadResponse):
+		return "bad_response"
+	case errors.Is(err, banshee.ErrRequestTimeout):
+		return "timeout"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "cancelled"
+	}
+	return "error"
+}
+
+// delayNow records the dynamic delay engine's searches currently wait
+// between requests.
+func (s *engineStats) delayNow(engine string, seconds float64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.delay == nil {
+		s.delay = map[string]float64{}
+	}
+	s.delay[engine] = seconds
+}
+
+// engineSummary prints per-engine result and request counts to stderr in
+// multi-engine mode, and decode retries, which point at a flaky proxy,
+// whenever there were any.
+func (c *Config) engineSummary() {
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+	multi := c.multiEngine()
+	if multi {
+		logInfo("[*] %s", versionString())
+	}
+	for _, e := range c.engines {
+		var retries string
+		if n := c.stats.retries[e]; n > 0 {
+			retries = fmt.Sprintf(", %d decode retries (%d gave up)", n, c.stats.gaveUp[e])
+		}
+		if multi {
+			logInfo("[*] %s: %d results, %d requests%s", e, c.stats.results[e], c.providers[e].QuotaState().Requests, retries)
+		} else if retries != "" {
+			logInfo("[*] %s: %s", e, retries[2:])
+		}
+	}
+}
+
+// quotaState sums the quota of every engine's provider.
+func (c *Config) quotaState() banshee.QuotaState {
+	var q banshee.QuotaState
+	for _, p := range c.providers {
+		s := p.QuotaState()
+		q.Requests += s.Requests
+		q.Usable += s.Usable
+		q.Keys += s.Keys
+	}
+	return q
+}
+
+var knownSources = []string{"crtsh", "wayback", "vt"}
+
+// parseSources splits the -sources value into a set, rejecting unknown
+// names.
+func parseSources(v string) (map[string]bool, error) {
+	out := map[string]bool{}
+	for _, s := range strings.Split(v, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		known := false
+		for _, k := range knownSources {
+			known = known || k == s
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown -sources entry %q (want %s)", s, strings.Join(knownSources, ", "))
+		}
+		out[s] = true
+	}
+	return out, nil
+}
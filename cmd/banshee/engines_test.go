@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// Every engine of -engine is searched at once, each from its own copy of
+// the Config but counting into the same stats; results both found are
+// merged. Run with -race.
+func TestMergeEngines(t *testing.T) {
+	pages := func(tag string) func(q banshee.Query) ([]string, error) {
+		return func(q banshee.Query) ([]string, error) {
+			if q.Start > 11 {
+				return nil, nil
+			}
+			links := fakeLinks(q, 2)
+			for i := range links {
+				links[i] += "/" + tag
+			}
+			return append(links, "https://example.com/both"), nil
+		}
+	}
+	google := newFakeProvider(pages("a"))
+	serp := newFakeProvider(pages("b"))
+	serp.name = "serpapi"
+	cfg := searchConfig(t, google, "-u", "example.com", "-q", "inurl:admin", "-concurrency", "2")
+	cfg.engines = []string{"google", "serpapi"}
+	cfg.providers["serpapi"] = serp
+
+	st := cfg.mergeEngines(context.Background(), "")
+	// two pages of two links from each engine, and the link both return
+	if got := len(st.store); got != 2*2*2+1 {
+		t.Errorf("merged %d results, want 9", got)
+	}
+	for _, e := range cfg.engines {
+		if got := cfg.stats.results[e]; got != 5 {
+			t.Errorf("stats for %s = %d, want 5", e, got)
+		}
+	}
+}
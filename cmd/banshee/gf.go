@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// --- gf-style URL pattern filters ---
+
+// gf-patterns.json maps a pattern name to a list of regular expressions; it is
+// also the format accepted by -gf-file.
+//
+//go:embed gf-patterns.json
+var builtinGFPatterns []byte
+
+// loadGFPatterns compiles the patterns selected with -gf from the built-in
+// set, overridden/extended by -gf-file when given.
+func loadGFPatterns(names, file string) ([]*regexp.Regexp, error) {
+	sets := map[string][]string{}
+	if err := json.Unmarshal(builtinGFPatterns, &sets); err != nil {
+		return nil, err
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		custom := map[string][]string{}
+		if err := json.Unmarshal(bytes.TrimPrefix(data, []byte(utf8BOM)), &custom); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for k, v := range custom {
+			sets[k] = v
+		}
+		if names == "" {
+			for k := range custom {
+				names += k + ","
+			}
+		}
+	}
+	var out []*regexp.Regexp
+	for _, name := range splitList(names) {
+		pats, ok := sets[name]
+		if !ok {
+			avail := make([]string, 0, len(sets))
+			for k := range sets {
+				avail = append(avail, k)
+			}
+			sort.Strings(avail)
+			return nil, fmt.Errorf("unknown -gf pattern %q (available: %s)", name, strings.Join(avail, ", "))
+		}
+		for _, p := range pats {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("pattern %s: %w", name, err)
+			}
+			out = append(out, re)
+		}
+	}
+	return out, nil
+}
+
+// gfFilter keeps only the results matching at least one selected pattern.
+func (c *Config) gfFilter(res []result) []result {
+	if len(c.gfPatterns) == 0 {
+		return res
+	}
+	out := res[:0]
+	for _, r := range res {
+		for _, re := range c.gfPatterns {
+			if re.MatchString(r.url) {
+				out = append(out, r)
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// --- Unique hosts output (-uh) ---
+
+// hostTally collects the hosts of the run's results under -uh, counting
+// the results on each, so every host is written once when the run ends.
+type hostTally struct {
+	mu         sync.Mutex
+	out        outputOptions
+	outputPath string
+	counts     bool // -uh-count
+	hits       map[string]int
+}
+
+var hostsRun *hostTally
+
+// add counts the results whose line survived probing ("" marks a dropped
+// one).
+func (t *hostTally) add(res []result, lines []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, r := range res {
+		if lines[i] == "" {
+			continue
+		}
+		if h := banshee.HostOf(r.url); h != "" {
+			t.hits[h]++
+		}
+	}
+}
+
+// flushHosts writes the hosts collected under -uh, as count<TAB>host with
+// -uh-count, to -o (only the ones not already there) or stdout.
+func flushHosts() {
+	t := hostsRun
+	if t == nil {
+		return
+	}
+	hostsRun = nil
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	hosts := make([]string, 0, len(t.hits))
+	for h := range t.hits {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	if !t.counts {
+		t.out.writeUnique(hosts, t.outputPath, exactKey)
+		return
+	}
+	lines := make([]string, len(hosts))
+	for i, h := range hosts {
+		lines[i] = strconv.Itoa(t.hits[h]) + "\t" + h
+	}
+	t.out.writeUnique(lines, t.outputPath, keyFunc{"counted-host", countedHostKey})
+}
+
+// countedHostKey compares -uh-count lines by host, so that a host already
+// in -o with another count is not written again.
+func countedHostKey(l string) string {
+	if _, h, ok := strings.Cut(l, "\t"); ok {
+		return h
+	}
+	return l
+}
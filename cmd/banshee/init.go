@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// --- banshee init ---
+
+// starterConfig is written by init -config. Every line is commented out,
+// so it changes nothing until edited.
+const starterConfig = `# banshee defaults, one "flag = value" per line (a bare boolean flag name
+# means true). Flags given on the command line override these.
+#
+# engine = google
+# pages = 2
+# delay = 1
+# workers = 2
+# proxy = http://127.0.0.1:8080
+# silent
+# no-color
+`
+
+// initMain sets up ~/.config/banshee: Google API keys, each checked with
+// one query, an optional CX and an optional starter config file. Without
+// -key it prompts for them.
+func initMain(args []string) {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	keyList := fs.String("key", "", "Google API key(s) to add, comma-separated (prompted for when omitted)")
+	cx := fs.String("cx", "", "Programmable Search Engine ID to save in the config file")
+	writeConfig := fs.Bool("config", false, "Also write a starter config file")
+	noVerify := fs.Bool("no-verify", false, "Save keys without a test query")
+	proxy := fs.String("proxy", "", "Specify an [protocol://]host[:port] proxy for the test queries")
+	fs.Parse(args)
+
+	dir, err := configDir()
+	if err != nil {
+		logErr("[!] init: %v", err)
+		os.Exit(exitFatal)
+	}
+	var keys []string
+	if *keyList != "" {
+		keys = splitCSV(*keyList)
+	} else {
+		in := bufio.NewReader(os.Stdin)
+		keys = promptKeys(in)
+		if *cx == "" {
+			*cx = prompt(in, "Programmable Search Engine ID (CX), empty for banshee's own: ")
+		}
+		if !*writeConfig {
+			*writeConfig = strings.HasPrefix(strings.ToLower(prompt(in, "Write a starter config file? [y/N]: ")), "y")
+		}
+	}
+	if len(keys) == 0 {
+		logErr("[!] init: no API keys given")
+		os.Exit(exitFatal)
+	}
+
+	if !*noVerify {
+		cl, err := buildHTTPClient(*proxy, proxyFlag)
+		if err != nil {
+			logErr("[!] Invalid proxy: %v", err)
+			os.Exit(exitFatal)
+		}
+		cl.Timeout = 30 * time.Second
+		keys = verifyKeys(cl, keys, *cx)
+		if len(keys) == 0 {
+			logErr("[!] init: no key passed the test query, nothing saved")
+			os.Exit(exitFatal)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		logErr("[!] init: %v", err)
+		os.Exit(exitFatal)
+	}
+	path := filepath.Join(dir, "keys.txt")
+	n, err := saveKeys(path, keys)
+	if err != nil {
+		logErr("[!] init: %v", err)
+		os.Exit(exitFatal)
+	}
+	fmt.Fprintf(infoOut, "[*] %d new key(s) saved to %s\n", n, path)
+
+	if *cx != "" || *writeConfig {
+		path := filepath.Join(dir, configFileName)
+		if err := saveConfig(path, *cx, *writeConfig); err != nil {
+			logErr("[!] init: %v", err)
+			os.Exit(exitFatal)
+		}
+		fmt.Fprintf(infoOut, "[*] config written to %s\n", path)
+	}
+}
+
+func splitCSV(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func prompt(in *bufio.Reader, q string) string {
+	fmt.Fprint(os.Stderr, q)
+	l, _ := in.ReadString('\n')
+	return strings.TrimSpace(l)
+}
+
+// promptKeys reads keys, one per line, until an empty line or EOF.
+func promptKeys(in *bufio.Reader) []string {
+	fmt.Fprintln(os.Stderr, "Enter Google Custom Search API keys, one per line; an empty line ends the list.")
+	var keys []string
+	for {
+		k := prompt(in, "API key: ")
+		if k == "" {
+			return keys
+		}
+		keys = append(keys, k)
+	}
+}
+
+// verifyKeys sends one query with each key and returns those that work.
+// A key that is only out of quota for today is kept.
+func verifyKeys(cl *http.Client, keys []string, cx string) []string {
+	var ok []string
+	for _, k := range keys {
+		p, err := banshee.NewProvider("google", []string{k}, banshee.ProviderOptions{HTTPClient: cl, CX: cx})
+		if err != nil {
+			logErr("[!] init: %v", err)
+			os.Exit(exitFatal)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		_, err = p.Search(ctx, banshee.Query{Text: "example", Start: 1})
+		cancel()
+		switch {
+		case err == nil:
+			fmt.Fprintf(infoOut, "[*] %s: ok\n", redactKey(k))
+			ok = append(ok, k)
+		case errors.Is(err, banshee.ErrKeyExhausted):
+			fmt.Fprintf(infoOut, "[*] %s: valid, but out of quota for today\n", redactKey(k))
+			ok = append(ok, k)
+		default:
+			logErr("[!] %s: rejected, not saved: %v", redactKey(k), strings.ReplaceAll(err.Error(), k, redactKey(k)))
+		}
+	}
+	return ok
+}
+
+// saveKeys adds keys missing from the key file at path, creating it, and
+// makes it readable by the owner only. It returns how many keys were added.
+func saveKeys(path string, keys []string) (int, error) {
+	var have []string
+	if fileExists(path) {
+		var err error
+		if have, err = readLines(path); err != nil {
+			return 0, err
+		}
+	}
+	seen := map[string]bool{}
+	for _, k := range have {
+		seen[k] = true
+	}
+	n := 0
+	for _, k := range keys {
+		if !seen[k] {
+			seen[k] = true
+			have = append(have, k)
+			n++
+		}
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(have, "\n")+"\n"), 0o600); err != nil {
+		return 0, err
+	}
+	return n, os.Chmod(path, 0o600)
+}
+
+// saveConfig sets cx in the config file at path, replacing an earlier cx
+// line. A missing file starts from starterConfig when starter is set.
+func saveConfig(path, cx string, starter bool) error {
+	var lines []string
+	switch b, err := os.ReadFile(path); {
+	case err == nil:
+		lines = strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	case os.IsNotExist(err):
+		if starter {
+			lines = strings.Split(strings.TrimRight(starterConfig, "\n"), "\n")
+		}
+	default:
+		return err
+	}
+	if cx != "" {
+		kept := lines[:0]
+		for _, l := range lines {
+			name, _, _ := strings.Cut(l, "=")
+			if strings.TrimLeft(strings.TrimSpace(name), "-") != "cx" {
+				kept = append(kept, l)
+			}
+		}
+		lines = append(kept, "cx = "+cx)
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+}
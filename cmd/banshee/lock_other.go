@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// lockFile is a no-op where flock isn't available: concurrent banshee
+// processes still write whole batches with single appends.
+func lockFile(*os.File) {}
+
+func unlockFile(*os.File) {}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Logging ---
+
+// logger mirrors console messages and records structured events to
+// -log-file, as text or one JSON object per line. Every registered secret
+// (API keys) is redacted from what reaches the file.
+type logger struct {
+	mu      sync.Mutex
+	w       io.Writer // nil without -log-file
+	json    bool
+	secrets []string
+}
+
+var lg = &logger{}
+
+// open starts writing events to path, appending.
+func (l *logger) open(path, format string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w = f
+	l.json = format == "json"
+	return nil
+}
+
+// addSecrets registers values that must never appear in the log file.
+func (l *logger) addSecrets(s ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, v := range s {
+		if v != "" {
+			l.secrets = append(l.secrets, v)
+		}
+	}
+}
+
+// redactKey shortens a key to its first and last 4 characters.
+func redactKey(k string) string {
+	if len(k) <= 8 {
+		return "****"
+	}
+	return k[:4] + "…" + k[len(k)-4:]
+}
+
+func (l *logger) redact(s string) string {
+	for _, k := range l.secrets {
+		if strings.Contains(s, k) {
+			s = strings.ReplaceAll(s, k, redactKey(k))
+		}
+	}
+	return s
+}
+
+// scrub redacts registered secrets from text leaving the process by other
+// means, such as notifications.
+func (l *logger) scrub(s string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.redact(s)
+}
+
+// event records one event with key/value context pairs.
+func (l *logger) event(name string, kv ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.w == nil {
+		return
+	}
+	fields := map[string]any{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		v := kv[i+1]
+		if s, ok := v.(string); ok {
+			v = l.redact(s)
+		}
+		fields[fmt.Sprint(kv[i])] = v
+	}
+	ts := time.Now().UTC().Format(time.RFC3339Nano)
+	if l.json {
+		fields["time"] = ts
+		fields["event"] = name
+		b, _ := json.Marshal(fields)
+		fmt.Fprintf(l.w, "%s\n", b)
+		return
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", ts, name)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%q", k, fmt.Sprint(fields[k]))
+	}
+	fmt.Fprintln(l.w, b.String())
+}
+
+// status is the -f progress line. On a terminal (with stdout redirected,
+// so results don't share the screen) it is rewritten in place, and console
+// messages clear it first and redraw it after; otherwise every update is a
+// plain stderr line.
+var status struct {
+	sync.Mutex
+	line    string
+	rewrite bool
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func initStatus() {
+	status.rewrite = isTerminal(os.Stderr) && !isTerminal(os.Stdout)
+}
+
+// setStatus shows line as the current progress.
+func setStatus(line string) {
+	status.Lock()
+	defer status.Unlock()
+	line = paint(true, line)
+	if !status.rewrite {
+		fmt.Fprintln(os.Stderr, line)
+		return
+	}
+	status.line = line
+	fmt.Fprint(os.Stderr, "\r\033[K"+line)
+}
+
+// clearStatus removes the progress line for good.
+func clearStatus() {
+	status.Lock()
+	defer status.Unlock()
+	if status.rewrite && status.line != "" {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+	status.line = ""
+}
+
+// consoleWrite prints msg on w without garbling the progress line.
+func consoleWrite(w io.Writer, msg string) {
+	msg = paint(w == os.Stderr, msg)
+	status.Lock()
+	defer status.Unlock()
+	if status.line == "" {
+		fmt.Fprintln(w, msg)
+		return
+	}
+	fmt.Fprint(os.Stderr, "\r\033[K")
+	fmt.Fprintln(w, msg)
+	fmt.Fprint(os.Stderr, status.line)
+}
+
+// commas formats n with thousands separators.
+func commas(n int64) string {
+	s := fmt.Sprint(n)
+	if n < 0 {
+		return "-" + commas(-n)
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// close flushes nothing (writes are unbuffered) but releases the file.
+func (l *logger) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if f, ok := l.w.(*os.File); ok {
+		f.Close()
+	}
+	l.w = nil
+}
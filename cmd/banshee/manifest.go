@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// --- Run manifest (-manifest) ---
+
+// runManifest describes a run for reproducibility: what ran, with which
+// configuration, against what, and where it wrote. It is written once, as
+// a single JSON document, when the run ends or is interrupted.
+type runManifest struct {
+	mu      sync.Mutex
+	path    string
+	start   time.Time
+	config  manifestConfig
+	targets []string
+}
+
+type manifestConfig struct {
+	Flags      map[string]string `json:"flags"`
+	ConfigFile string            `json:"config_file,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+}
+
+type manifestDoc struct {
+	Version     string                    `json:"version"`
+	Start       string                    `json:"start"`
+	End         string                    `json:"end"`
+	Interrupted bool                      `json:"interrupted"`
+	Config      manifestConfig            `json:"config"`
+	Engines     []string                  `json:"engines"`
+	Targets     []string                  `json:"targets"`
+	Requests    map[string]map[string]int `json:"key_requests"` // engine -> key fingerprint -> requests
+	Outputs     map[string]string         `json:"outputs"`
+}
+
+var manifestRun *runManifest
+
+// secretFlags hold credentials; the manifest shows them like the log file
+// shows API keys.
+var secretFlags = map[string]bool{
+	"vt-key": true, "webhook": true, "webhook-secret": true, "slack-webhook": true,
+	"discord-webhook": true, "telegram-token": true,
+}
+
+// manifestEnv are the environment variables that change what a run does.
+// The ones holding credentials are redacted.
+var manifestEnv = map[string]bool{
+	keyFileEnv: false, "HTTP_PROXY": false, "HTTPS_PROXY": false, "NO_PROXY": false,
+	"http_proxy": false, "https_proxy": false, "no_proxy": false, "NO_COLOR": false,
+	"SHODAN_API_KEY": true, "VT_API_KEY": true, "SLACK_WEBHOOK_URL": true,
+	"DISCORD_WEBHOOK_URL": true, "TELEGRAM_BOT_TOKEN": true, "TELEGRAM_CHAT_ID": false,
+}
+
+// newManifest snapshots the effective value of every flag of fs, whether
+// it came from the command line, the config file or its default, once
+// they are parsed. Aliases (-o and -output) are listed once, under the
+// long name.
+func newManifest(path string, fs *flag.FlagSet) *runManifest {
+	m := &runManifest{path: path, start: time.Now()}
+	m.config.Flags = map[string]string{}
+	m.config.ConfigFile = configLoaded
+	names := map[uintptr]string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if v := reflect.ValueOf(f.Value); v.Kind() == reflect.Pointer {
+			if prev, ok := names[v.Pointer()]; ok {
+				if len(prev) >= len(f.Name) {
+					return
+				}
+				delete(m.config.Flags, prev)
+			}
+			names[v.Pointer()] = f.Name
+		}
+		m.config.Flags[f.Name] = manifestValue(f.Name, f.Value.String())
+	})
+	for name, secret := range manifestEnv {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if m.config.Env == nil {
+			m.config.Env = map[string]string{}
+		}
+		if secret && v != "" {
+			v = redactKey(v)
+		}
+		m.config.Env[name] = redactURL(v)
+	}
+	return m
+}
+
+// manifestValue is the value of flag name as the manifest shows it.
+func manifestValue(name, v string) string {
+	if secretFlags[name] && v != "" {
+		return redactKey(v)
+	}
+	if name == "proxy" || name == "r" {
+		return redactURL(v)
+	}
+	return v
+}
+
+// redactURL hides the password of a URL with credentials, such as a proxy.
+func redactURL(v string) string {
+	if u, err := url.Parse(v); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return v
+}
+
+// target records a target the run searched.
+func (m *runManifest) target(t string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.targets = append(m.targets, t)
+	m.mu.Unlock()
+}
+
+// writeManifest writes -manifest, replacing the file in one step so a
+// reader never sees half of it.
+func (c *Config) writeManifest(interrupted bool) {
+	m := manifestRun
+	if m == nil {
+		return
+	}
+	manifestRun = nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	doc := manifestDoc{
+		Version:     versionString(),
+		Start:       m.start.UTC().Format(time.RFC3339),
+		End:         time.Now().UTC().Format(time.RFC3339),
+		Interrupted: interrupted,
+		Config:      m.config,
+		Targets:     m.targets,
+		Requests:    map[string]map[string]int{},
+		Outputs:     c.outputArtifacts(),
+	}
+	if doc.Targets == nil {
+		doc.Targets = []string{}
+	}
+	for name, p := range c.providers {
+		doc.Engines = append(doc.Engines, name)
+		kc, ok := p.(banshee.KeyCounter)
+		if !ok {
+			continue
+		}
+		per := map[string]int{}
+		for k, n := range kc.KeyRequests() {
+			per[keyFingerprint(k)] = n
+		}
+		doc.Requests[name] = per
+	}
+	sort.Strings(doc.Engines)
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		logErr("[!] cannot write manifest: %v", err)
+		return
+	}
+	b = []byte(lg.scrub(string(b)) + "\n")
+	err = os.WriteFile(m.path+".part", b, 0o644)
+	if err == nil {
+		err = os.Rename(m.path+".part", m.path)
+	}
+	if err != nil {
+		logErr("[!] cannot write manifest: %v", err)
+	}
+}
+
+// outputArtifacts are the files and directories the run wrote to, by
+// what they hold.
+func (c *Config) outputArtifacts() map[string]string {
+	out := map[string]string{}
+	for name, p := range map[string]string{
+		"results": c.outputPath, "audit": c.auditFile, "summary": c.summaryFile, "log": c.logFile,
+		"skipped": c.skippedFile, "no_results": c.noResultsFile, "errors": c.errorsFile,
+		"resume": c.resumeFile, "diff_missing": c.diffMissing, "downloads": c.downloadDir,
+	} {
+		if p != "" {
+			out[name] = p
+		}
+	}
+	return out
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// --- banshee merge ---
+
+// mergeBatch is how many input lines are deduplicated and appended at a
+// time.
+const mergeBatch = 10000
+
+// mergeMain implements "banshee merge OUT IN...": it appends to OUT the
+// lines of every IN that OUT doesn't have yet, like -o does with results.
+// Inputs are streamed and only fingerprints of the lines seen are kept, so
+// the files can be far larger than memory.
+func mergeMain(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	loose := fs.Bool("dedupe-loose", false, "Deduplicate ignoring the scheme and a leading www.")
+	ci := fs.Bool("dedupe-ci", false, "Deduplicate ignoring case in URL paths and queries (hosts always are)")
+	uniqueParams := fs.Bool("unique-params", false, "Deduplicate by host, path and parameter names (?id=1 == ?id=2)")
+	strip := fs.Bool("strip-params", false, "Remove tracking parameters (utm_*, gclid, fbclid, ...) from lines")
+	stripExtra := fs.String("strip-params-extra", "", "Additional parameter names to remove (comma-separated or file, implies -strip-params)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: banshee merge [flags] OUT IN... (IN may be gzipped, - reads stdin)")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		fs.Usage()
+		os.Exit(exitFatal)
+	}
+	out, inputs := fs.Arg(0), fs.Args()[1:]
+
+	f := banshee.Filter{LooseDedupe: *loose, CaseInsensitive: *ci, UniqueParams: *uniqueParams}
+	if *strip || *stripExtra != "" {
+		f.StripParams = buildStripSet(*stripExtra)
+	}
+
+	// fingerprints instead of lines, see fingerprint
+	s, err := openSink(out, outputOptions{results: out, compact: true})
+	if err != nil {
+		logErr("[!] merge: cannot open %s: %v", out, err)
+		os.Exit(exitFatal)
+	}
+	failed := false
+	var total int64
+	for _, in := range inputs {
+		if in != "-" && sameFile(in, out) {
+			logErr("[!] merge: skipping %s, it is the output file", in)
+			continue
+		}
+		read, added, err := mergeInput(s, in, f.StripParams, keyFunc{"url", f.Key})
+		total += added
+		if err != nil {
+			logErr("[!] merge: %s: %v", in, err)
+			failed = true
+		}
+		fmt.Fprintf(infoOut, "[*] %s: %s lines, %s new\n", in, commas(read), commas(added))
+	}
+	closeOutput(out)
+	if err := outputFailed(); err != nil {
+		os.Exit(exitFatal)
+	}
+	fmt.Fprintf(infoOut, "[*] %s: %s lines added\n", out, commas(total))
+	if failed {
+		os.Exit(exitFatal)
+	}
+}
+
+// mergeInput appends the new lines of the file in to s, stripping the
+// parameters in strip first, and returns how many lines it read and how
+// many of them were new.
+func mergeInput(s *outputSink, in string, strip map[string]struct{}, key keyFunc) (read, added int64, err error) {
+	var r io.Reader = os.Stdin
+	if in != "-" {
+		f, err := os.Open(in)
+		if err != nil {
+			return 0, 0, err
+		}
+		defer f.Close()
+		r = f
+	}
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return 0, 0, err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+	batch := make([]string, 0, mergeBatch)
+	write := func() {
+		added += int64(len(s.writeNew(batch, key)))
+		batch = batch[:0]
+	}
+	sc := newLineScanner(r)
+	for sc.Scan() {
+		l := strings.TrimSpace(sc.Text())
+		if l == "" {
+			continue
+		}
+		read++
+		if strip != nil {
+			l = banshee.StripParams(l, strip)
+		}
+		if batch = append(batch, l); len(batch) == mergeBatch {
+			write()
+		}
+	}
+	write()
+	return read, added, sc.Err()
+}
+
+// sameFile reports whether a and b name the same existing file.
+func sameFile(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	if err != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(fa, fb)
+}
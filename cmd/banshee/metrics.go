@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// --- Prometheus metrics (serve) ---
+
+// dailyQuota is the free daily quota of one key, for the engines that have
+// a fixed one, from which the remaining-quota estimates are computed.
+var dailyQuota = map[string]int{"google": 100}
+
+// metrics serves /metrics in the Prometheus text format. Every value is
+// read at scrape time from the state the stats summary and the job list
+// use, so nothing has to be registered or kept in sync.
+func (s *server) metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.base.writeMetrics(w, len(s.slots))
+}
+
+// writeMetrics writes the metrics of c's engines, with active the number of
+// jobs running.
+func (c *Config) writeMetrics(w io.Writer, active int) {
+	st := c.stats
+	st.mu.Lock()
+	requests := make([][2]string, 0, len(st.requests))
+	for k := range st.requests {
+		requests = append(requests, k)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i][0]+"\x00"+requests[i][1] < requests[j][0]+"\x00"+requests[j][1]
+	})
+
+	metricHeader(w, "banshee_api_requests_total", "counter", "Search API requests, by engine and outcome.")
+	for _, k := range requests {
+		fmt.Fprintf(w, "banshee_api_requests_total{engine=%q,outcome=%q} %d\n", k[0], k[1], st.requests[k])
+	}
+	metricHeader(w, "banshee_results_total", "counter", "Results found, by engine.")
+	for _, e := range c.engines {
+		fmt.Fprintf(w, "banshee_results_total{engine=%q} %d\n", e, st.results[e])
+	}
+	metricHeader(w, "banshee_dynamic_delay_seconds", "gauge", "Delay between requests of the latest search, by engine.")
+	for _, e := range c.engines {
+		if d, ok := st.delay[e]; ok {
+			fmt.Fprintf(w, "banshee_dynamic_delay_seconds{engine=%q} %g\n", e, d)
+		}
+	}
+	st.mu.Unlock()
+
+	metricHeader(w, "banshee_keys", "gauge", "API keys loaded, by engine.")
+	for _, e := range c.engines {
+		fmt.Fprintf(w, "banshee_keys{engine=%q} %d\n", e, c.providers[e].QuotaState().Keys)
+	}
+	metricHeader(w, "banshee_keys_exhausted", "gauge", "API keys out of quota, by engine.")
+	for _, e := range c.engines {
+		q := c.providers[e].QuotaState()
+		fmt.Fprintf(w, "banshee_keys_exhausted{engine=%q} %d\n", e, q.Keys-q.Usable)
+	}
+	metricHeader(w, "banshee_key_quota_remaining", "gauge",
+		"Estimated requests left today per key (by fingerprint), for engines with a known daily quota.")
+	for _, e := range c.engines {
+		quota, ok := dailyQuota[e]
+		kc, counts := c.providers[e].(banshee.KeyCounter)
+		if !ok || !counts {
+			continue
+		}
+		for _, u := range kc.KeyUsage() {
+			left := max(quota-u.Today, 0)
+			if u.Exhausted {
+				left = 0
+			}
+			fmt.Fprintf(w, "banshee_key_quota_remaining{engine=%q,key=%q} %d\n", e, keyFingerprint(u.Key), left)
+		}
+	}
+	metricHeader(w, "banshee_active_jobs", "gauge", "Jobs running.")
+	fmt.Fprintf(w, "banshee_active_jobs %d\n", active)
+}
+
+func metricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// --- Mode chaining (-modes) ---
+
+// knownModes are the -modes names, each selecting one attack. A mode takes
+// its input from the usual flag: files from -e, dirs from -w, contents
+// from -c and dork from -q.
+var knownModes = []string{"subs", "files", "dirs", "contents", "dork"}
+
+// parseModes splits the -modes value, rejecting unknown or repeated names.
+func parseModes(v string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	for _, m := range strings.Split(v, ",") {
+		m = strings.ToLower(strings.TrimSpace(m))
+		if m == "" {
+			continue
+		}
+		known := false
+		for _, k := range knownModes {
+			known = known || k == m
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown -modes entry %q (want %s)", m, strings.Join(knownModes, ", "))
+		}
+		if seen[m] {
+			return nil, fmt.Errorf("-modes lists %s twice", m)
+		}
+		seen[m] = true
+		out = append(out, m)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("-modes is empty")
+	}
+	return out, nil
+}
+
+// checkModes makes sure every listed mode has its input flag and that no
+// input flag is left without its mode.
+func (c *Config) checkModes() error {
+	inputs := map[string]struct {
+		flag string
+		set  bool
+	}{
+		"files":    {"-e", c.extension != ""},
+		"dirs":     {"-w", c.dictionary != ""},
+		"contents": {"-c", c.contents != ""},
+		"dork":     {"-q", c.dork != ""},
+		"subs":     {"-s", c.subdomainMode},
+	}
+	listed := map[string]bool{}
+	for _, m := range c.modeList {
+		listed[m] = true
+		if in := inputs[m]; m != "subs" && !in.set {
+			return fmt.Errorf("-modes %s needs %s", m, in.flag)
+		}
+	}
+	for _, m := range knownModes {
+		if in := inputs[m]; in.set && !listed[m] {
+			return fmt.Errorf("%s is given but -modes doesn't list %s", in.flag, m)
+		}
+	}
+	if c.chain && !listed["subs"] {
+		return errors.New("-chain needs subs in -modes")
+	}
+	return nil
+}
+
+// flagModes lists the modes selected by flags alone, without -modes.
+func (c *Config) flagModes() []string {
+	var m []string
+	if c.dictionary != "" {
+		m = append(m, "-w")
+	}
+	if c.extension != "" {
+		m = append(m, "-e")
+	}
+	if c.subdomainMode {
+		m = append(m, "-s")
+	}
+	if c.contents != "" {
+		m = append(m, "-c")
+	}
+	if c.dork != "" {
+		m = append(m, "-q")
+	}
+	return m
+}
+
+// runModes runs each of -modes in order against the target. Results are
+// deduplicated across modes (and, with -f, across targets), and with
+// -chain the modes after subs also run against every subdomain it found.
+func (c *Config) runModes(ctx context.Context) {
+	if c.seen == nil {
+		c.seen = NewSafeSet(c.output().compact)
+	}
+	targets := []*Config{c}
+	for _, m := range c.modeList {
+		for _, t := range targets {
+			if ctx.Err() != nil {
+				return
+			}
+			hosts := t.runMode(ctx, m)
+			if m != "subs" || !c.chain {
+				continue
+			}
+			for _, h := range hosts {
+				if h == c.target {
+					continue
+				}
+				c2 := *c
+				c2.target = h
+				c2.announced = false
+				targets = append(targets, &c2)
+			}
+		}
+	}
+}
+
+// runMode runs mode m alone: the other modes' inputs are cleared so they
+// don't leak into its queries. subs returns the hosts it found.
+func (c *Config) runMode(ctx context.Context, m string) []string {
+	c.announce()
+	c2 := *c
+	c2.subdomainMode = m == "subs"
+	if m != "files" {
+		c2.extension = ""
+	}
+	if m != "dirs" {
+		c2.dictionary, c2.inUrl = "", nil
+	}
+	if m != "contents" {
+		c2.contents, c2.inFile = "", ""
+	}
+	if m != "dork" {
+		c2.dork = ""
+	}
+	switch m {
+	case "subs":
+		return c2.subdomainAttack(ctx)
+	case "files":
+		c2.extensionAttack(ctx)
+	case "dirs":
+		c2.dictionaryAttack(ctx)
+	case "contents":
+		c2.contentsAttack(ctx)
+	case "dork":
+		emit := func(res []result) { c2.emit(ctx, res) }
+		if res := c2.urlRun(ctx, "", emit); len(res) > 0 {
+			emit(res)
+		}
+	}
+	return nil
+}
+
+// announce prints the target header, once per target under -modes.
+func (c *Config) announce() {
+	if c.announced {
+		return
+	}
+	logv(c.verbose, "Target: %s", c.target)
+	c.announced = c.modeList != nil
+}
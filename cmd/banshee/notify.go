@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Notifications about new results ---
+
+// runSummary describes a finished (or interrupted) run for notifications.
+type runSummary struct {
+	RunID    string   `json:"run_id"`
+	Target   string   `json:"target"`
+	Mode     string   `json:"mode"`
+	New      int      `json:"new_results"`
+	Sample   []string `json:"sample,omitempty"`
+	Complete bool     `json:"complete"`
+	Errors   int64    `json:"errors"`
+	Elapsed  string   `json:"elapsed"`
+	Version  string   `json:"version"`
+}
+
+var (
+	runID    = newRunID()
+	runStart = time.Now()
+)
+
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// newResults records result lines that were not in the output before,
+// keeping the first few as a sample.
+var newResults = struct {
+	sync.Mutex
+	n      int
+	sample []string
+}{}
+
+const maxSample = 100
+
+func recordNew(lines []string) {
+	newResults.Lock()
+	defer newResults.Unlock()
+	newResults.n += len(lines)
+	for _, l := range lines {
+		if len(newResults.sample) == maxSample {
+			break
+		}
+		newResults.sample = append(newResults.sample, l)
+	}
+}
+
+// modeName lists the selected modes, e.g. "extensions+subdomains".
+func (c *Config) modeName() string {
+	var m []string
+	if c.dictionary != "" {
+		m = append(m, "dictionary")
+	}
+	if c.extension != "" {
+		m = append(m, "extensions")
+	}
+	if c.subdomainMode {
+		m = append(m, "subdomains")
+	}
+	if c.contents != "" {
+		m = append(m, "contents")
+	}
+	if c.dork != "" {
+		m = append(m, "dork")
+	}
+	return strings.Join(m, "+")
+}
+
+func (c *Config) summary(complete bool, sample int) runSummary {
+	newResults.Lock()
+	defer newResults.Unlock()
+	s := runSummary{
+		RunID:    runID,
+		Target:   c.target,
+		Mode:     c.modeName(),
+		New:      newResults.n,
+		Complete: complete,
+		Errors:   errorsLogged.Load(),
+		Elapsed:  time.Since(runStart).Round(time.Second).String(),
+		Version:  version,
+	}
+	if c.domainsFile != "" {
+		s.Target = c.domainsFile
+	}
+	if sample > len(newResults.sample) {
+		sample = len(newResults.sample)
+	}
+	for _, l := range newResults.sample[:sample] {
+		s.Sample = append(s.Sample, lg.scrub(l))
+	}
+	return s
+}
+
+var knownNotifiers = []string{"slack", "discord", "telegram"}
+
+// parseNotifiers splits the -notify value into a set, rejecting unknown
+// names.
+func parseNotifiers(v string) (map[string]bool, error) {
+	out := map[string]bool{}
+	for _, s := range strings.Split(v, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		known := false
+		for _, k := range knownNotifiers {
+			known = known || k == s
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown -notify entry %q (want %s)", s, strings.Join(knownNotifiers, ", "))
+		}
+		out[s] = true
+	}
+	return out, nil
+}
+
+// checkNotifiers makes sure every selected notifier has what it needs to
+// deliver, so a misconfiguration shows up before the run rather than after.
+func (c *Config) checkNotifiers() error {
+	switch c.notifyOn {
+	case "new", "always", "errors":
+	default:
+		return fmt.Errorf("-notify-on must be new, always or errors")
+	}
+	if c.notifiers["slack"] && c.slackWebhook == "" {
+		return fmt.Errorf("-notify slack needs -slack-webhook or $SLACK_WEBHOOK_URL")
+	}
+	if c.notifiers["discord"] && c.discordWebhook == "" {
+		return fmt.Errorf("-notify discord needs -discord-webhook or $DISCORD_WEBHOOK_URL")
+	}
+	if c.notifiers["telegram"] && (c.telegramToken == "" || c.telegramChat == "") {
+		return fmt.Errorf("-notify telegram needs -telegram-token and -telegram-chat (or $TELEGRAM_BOT_TOKEN and $TELEGRAM_CHAT_ID)")
+	}
+	return nil
+}
+
+// notify sends the configured notifications for the run. Delivery problems
+// are logged and never change the outcome of the run.
+func (c *Config) notify(complete bool) {
+	if c.webhookURL != "" {
+		s := c.summary(complete, c.webhookSample)
+		if s.New > 0 && s.New >= c.webhookThreshold {
+			if err := c.postWebhook(s); err != nil {
+				logErr("[!] webhook: %v", err)
+			}
+		}
+	}
+	if len(c.notifiers) == 0 {
+		return
+	}
+	s := c.summary(complete, 10)
+	switch c.notifyOn {
+	case "new":
+		if s.New == 0 {
+			return
+		}
+	case "errors":
+		if s.Errors == 0 && s.Complete && !keysRanOut.Load() {
+			return
+		}
+	}
+	if c.notifiers["slack"] {
+		if err := c.notifySlack(s); err != nil {
+			logErr("[!] slack: %v", err)
+		}
+	}
+	if c.notifiers["discord"] {
+		if err := c.notifyDiscord(s); err != nil {
+			logErr("[!] discord: %v", err)
+		}
+	}
+	if c.notifiers["telegram"] {
+		// The bot token is part of the API URL and would show up in errors
+		if err := c.notifyTelegram(c.summary(complete, c.telegramLines)); err != nil {
+			logErr("[!] telegram: %s", lg.scrub(err.Error()))
+		}
+	}
+}
+
+// headline is the one-line description of s shared by chat notifications.
+func (s runSummary) headline() string {
+	state := "finished"
+	if !s.Complete {
+		state = "stopped early"
+	}
+	mode := s.Mode
+	if mode == "" {
+		mode = "search"
+	}
+	h := fmt.Sprintf("banshee %s on %s (%s): %d new result(s) in %s", state, s.Target, mode, s.New, s.Elapsed)
+	if s.Errors > 0 {
+		h += fmt.Sprintf(", %d error(s)", s.Errors)
+	}
+	return h
+}
+
+// truncateSample joins sample lines, cutting whole lines so the result fits
+// in max bytes.
+func truncateSample(sample []string, max int) string {
+	var b strings.Builder
+	for i, l := range sample {
+		if b.Len()+len(l)+1 > max {
+			if rest := fmt.Sprintf("... %d more", len(sample)-i); b.Len()+len(rest) <= max {
+				b.WriteString(rest)
+			}
+			break
+		}
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// postWebhook POSTs s as JSON, signed with -webhook-secret when set.
+func (c *Config) postWebhook(s runSummary) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+	if c.webhookSecret != "" {
+		m := hmac.New(sha256.New, []byte(c.webhookSecret))
+		m.Write(body)
+		headers["X-Banshee-Signature"] = "sha256=" + hex.EncodeToString(m.Sum(nil))
+	}
+	return c.postWithRetry(c.webhookURL, body, headers)
+}
+
+// postWithRetry POSTs body to u, retrying network errors and 5xx responses
+// with exponential backoff, and 429 responses after the delay the service
+// asks for.
+func (c *Config) postWithRetry(u string, body []byte, headers map[string]string) error {
+	backoff := time.Second
+	wait := time.Duration(0)
+	var lastErr error
+	for attempt := 0; attempt < 4; attempt++ {
+		if attempt > 0 {
+			if wait == 0 {
+				wait = backoff
+				backoff *= 2
+			}
+			time.Sleep(wait)
+			wait = 0
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+		if err != nil {
+			cancel()
+			return err
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		req.Header.Set("User-Agent", "banshee/"+version)
+		resp, err := c.client.Do(req)
+		cancel()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		rb, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait = retryAfter(resp.Header.Get("Retry-After"), rb)
+			lastErr = fmt.Errorf("rate limited")
+			continue
+		case resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("status %d", resp.StatusCode)
+			continue
+		case resp.StatusCode >= 300:
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// retryAfter reads the delay a rate-limited service asks for, from the
+// retry_after field Discord and Telegram put in the body or from the
+// Retry-After header, capped at a minute.
+func retryAfter(header string, body []byte) time.Duration {
+	var r struct {
+		RetryAfter float64 `json:"retry_after"`
+		Parameters struct {
+			RetryAfter float64 `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	json.Unmarshal(body, &r)
+	secs := r.RetryAfter
+	if secs == 0 {
+		secs = r.Parameters.RetryAfter
+	}
+	if secs == 0 {
+		secs, _ = strconv.ParseFloat(header, 64)
+	}
+	d := time.Duration(secs * float64(time.Second))
+	if d <= 0 {
+		d = time.Second
+	}
+	return min(d, time.Minute)
+}
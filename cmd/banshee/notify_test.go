@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// -notify-on errors stays quiet after a run that went well, even though
+// -diff reports what changed on stderr, and speaks up once something fails.
+func TestNotifyOnErrors(t *testing.T) {
+	var posts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+	}))
+	defer srv.Close()
+
+	base := writeFile(t, "baseline.txt", "https://example.com/gone\n")
+	p := newFakeProvider(nil)
+	cfg := searchConfig(t, p, "-u", "example.com", "-q", "inurl:admin", "-p", "1", "-diff", base,
+		"-notify", "slack", "-slack-webhook", srv.URL, "-notify-on", "errors")
+	var err error
+	if cfg.notifiers, err = parseNotifiers(cfg.notifyList); err != nil {
+		t.Fatal(err)
+	}
+	cfg.client = srv.Client()
+	useOutput(t, cfg)
+	diffRun = &diffState{baseline: base, outputPath: cfg.outputPath, seen: map[string]bool{}, lines: map[string][]string{}}
+	errorsLogged.Store(0)
+	t.Cleanup(func() {
+		diffRun = nil
+		errorsLogged.Store(0)
+	})
+
+	cfg.runTarget(context.Background())
+	flushDiff(true)
+	cfg.notify(true)
+	if n := posts.Load(); n != 0 {
+		t.Errorf("a clean -diff run sent %d notification(s), want none", n)
+	}
+
+	logErr("[!] something failed")
+	cfg.notify(true)
+	if n := posts.Load(); n != 1 {
+		t.Errorf("a run with an error sent %d notification(s), want 1", n)
+	}
+}
@@ -0,0 +1,526 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// --- Output ---
+
+// keyFunc is a named deduplication key. A file written under several keys,
+// say URLs by dedupeKey and -count lines whole, keeps a set of keys for
+// each name, so lines are always compared by their writer's key.
+type keyFunc struct {
+	name string
+	fn   func(string) string // nil compares whole lines
+}
+
+// exactKey compares whole lines.
+var exactKey keyFunc
+
+func (k keyFunc) of(l string) string {
+	if k.fn == nil {
+		return l
+	}
+	return k.fn(l)
+}
+
+// outputOptions are how a run writes its lines: which file holds its
+// results and the flags that shape every file it writes. The CLI has one
+// run per process; every serve job is a run of its own.
+type outputOptions struct {
+	results  string // -o, "" for stdout; -skipped-file and the like are not results
+	stamp    bool   // -timestamps
+	compact  bool   // -flush-every and -fast-dedupe, see fingerprint
+	fallback bool   // -fallback-stdout
+	rotation rotatePolicy
+	// counter, when set, also counts the results written.
+	counter *atomic.Int64
+}
+
+// output returns the output options of c's run.
+func (c *Config) output() outputOptions {
+	return outputOptions{
+		results:  c.outputPath,
+		stamp:    c.timestamps,
+		compact:  c.flushEvery > 0 || c.fastDedupe,
+		fallback: c.fallbackStdout,
+		rotation: c.rotation,
+		counter:  c.written,
+	}
+}
+
+// outputOrPrintUnique prints urls or appends the new ones to outputPath,
+// deduplicated by key.
+func (o outputOptions) outputOrPrintUnique(urls []string, outputPath string, key keyFunc) {
+	uniq := banshee.UniqueByKey(urls, key.of)
+	sort.Strings(uniq)
+	o.writeUnique(uniq, outputPath, key)
+}
+
+// writeUnique prints lines in order, or appends those not already present
+// by key to outputPath.
+func (o outputOptions) writeUnique(uniq []string, outputPath string, key keyFunc) {
+	if d := diffRun; d != nil && outputPath == d.outputPath {
+		d.add(uniq, key)
+		return
+	}
+	if o.stamp {
+		inner := key
+		key.fn = func(l string) string { return inner.of(unstamp(l)) }
+	}
+	results := outputPath == o.results
+	written := func(lines []string) {
+		if results {
+			saved.Add(int64(len(lines)))
+			recordNew(lines)
+			if o.counter != nil {
+				o.counter.Add(int64(len(lines)))
+			}
+		}
+	}
+	if results {
+		found.Add(int64(len(uniq)))
+	}
+	if outputPath == "" {
+		if o.compact {
+			stdoutSeen.Lock()
+			uniq = stdoutSeen.filter(uniq, key)
+			stdoutSeen.Unlock()
+		}
+		for _, u := range uniq {
+			fmt.Println(stamp(u, o.stamp))
+		}
+		written(uniq)
+		return
+	}
+	s, err := openSink(outputPath, o)
+	if err != nil {
+		if !o.fallback {
+			failOutput(fmt.Errorf("cannot open output file: %w", err))
+			return
+		}
+		logErr("[!] cannot open output file: %v", err)
+		for _, u := range uniq {
+			fmt.Println(stamp(u, o.stamp))
+		}
+		written(uniq)
+		return
+	}
+	written(s.writeNew(uniq, key))
+}
+
+// saved counts results written to stdout or -o, for the interruption
+// note. found also counts results the output file already had, for the
+// exit code.
+var saved, found atomic.Int64
+
+func savedResults() int64 {
+	return saved.Load()
+}
+
+// outputSink owns one -o file for the lifetime of the process: the keys of
+// its lines are read once per keyFunc, and new lines are buffered and
+// appended in batches, emulating "anew" without re-reading the file on
+// every write. Every batch
+// is appended with one write under an exclusive file lock, after reading
+// what other processes appended since the last batch, so banshee runs
+// sharing an -o file neither tear lines nor (mostly) duplicate them.
+type outputSink struct {
+	mu   sync.Mutex
+	f    *os.File
+	off  int64 // file size after the last batch, where other writers' lines start
+	pend []pendingLine
+	size int                // bytes in pend
+	sets map[string]*keySet // by keyFunc name, built on first use
+	opts outputOptions      // of the run that opened the file
+
+	rotate bool   // the results file under -rotate
+	day    string // the day the file started, for -rotate daily
+}
+
+// keySet holds the keys, under one keyFunc, of the lines in the file: the
+// keys themselves or, under -fast-dedupe, their fingerprints.
+type keySet struct {
+	key  keyFunc
+	m    map[string]struct{}
+	base []uint64 // sorted fingerprints of the lines the file had, under -fast-dedupe
+	fps  fpSet    // and of those added since
+}
+
+// add records k and reports whether it was new.
+func (ks *keySet) add(k string) bool {
+	if ks.m != nil {
+		if _, ok := ks.m[k]; ok {
+			return false
+		}
+		ks.m[k] = struct{}{}
+		return true
+	}
+	fp := fingerprint(k)
+	if _, old := slices.BinarySearch(ks.base, fp); old {
+		return false
+	}
+	if _, ok := ks.fps[fp]; ok {
+		return false
+	}
+	ks.fps[fp] = struct{}{}
+	return true
+}
+
+// pendingLine is a line accepted as new but not yet written, with the
+// deduplication key it was accepted under and the name of that key.
+type pendingLine struct {
+	text string
+	key  string
+	set  string
+}
+
+// sinkBatch is the buffered size at which a batch is written.
+const sinkBatch = 64 << 10
+
+var sinks = struct {
+	sync.Mutex
+	m map[string]*outputSink
+}{m: map[string]*outputSink{}}
+
+// openSink returns the sink of path, opening it for a run with options o
+// unless it is open already.
+func openSink(path string, o outputOptions) (*outputSink, error) {
+	sinks.Lock()
+	defer sinks.Unlock()
+	if s, ok := sinks.m[path]; ok {
+		return s, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &outputSink{f: f, sets: map[string]*keySet{}, opts: o, rotate: path == o.results && o.rotation != rotatePolicy{}, day: dayOf(time.Now())}
+	lockFile(f)
+	if fi, err := f.Stat(); err == nil && fi.Size() > 0 {
+		s.day = dayOf(fi.ModTime())
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, fi.Size()-1); err == nil && last[0] != '\n' {
+			f.Write([]byte{'\n'}) // finish a last line left without one
+		}
+	}
+	// the lines are read into a keySet by the first write under each key
+	if fi, err := f.Stat(); err == nil {
+		s.off = fi.Size()
+	}
+	unlockFile(f)
+	sinks.m[path] = s
+	return s, nil
+}
+
+// readSince returns the complete lines appended to the file after s.off
+// and moves s.off past them. A partial last line is left for later.
+func (s *outputSink) readSince() []string {
+	fi, err := s.f.Stat()
+	if err != nil || fi.Size() <= s.off {
+		return nil
+	}
+	b := make([]byte, fi.Size()-s.off)
+	n, _ := s.f.ReadAt(b, s.off)
+	b = b[:n]
+	if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+		b = b[:i+1]
+	} else {
+		return nil
+	}
+	s.off += int64(len(b))
+	var out []string
+	for _, l := range strings.Split(string(b), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// writeNew appends the lines whose key isn't in the file yet and returns
+// them.
+func (s *outputSink) writeNew(lines []string, key keyFunc) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ks := s.keySet(key)
+	var fresh []string
+	for _, l := range lines {
+		k := key.of(l)
+		if !ks.add(k) {
+			continue
+		}
+		s.remember(l, key.name)
+		s.queue(pendingLine{text: l, key: k, set: key.name})
+		fresh = append(fresh, l)
+	}
+	return fresh
+}
+
+// keySet returns the set of keys under key, streaming the file written so
+// far and the pending lines into it on first use. Under -fast-dedupe only
+// fingerprints are kept, so memory stays proportional to the number of
+// distinct results rather than their size.
+func (s *outputSink) keySet(key keyFunc) *keySet {
+	if ks, ok := s.sets[key.name]; ok {
+		return ks
+	}
+	ks := &keySet{key: key}
+	if !s.opts.compact {
+		ks.m = map[string]struct{}{}
+	}
+	sc := newLineScanner(io.NewSectionReader(s.f, 0, s.off))
+	for sc.Scan() {
+		l := strings.TrimSpace(sc.Text())
+		switch {
+		case l == "":
+		case ks.m != nil:
+			ks.m[key.of(l)] = struct{}{}
+		default:
+			// a sorted slice takes 8 bytes per line, a fraction of
+			// what a map of millions of fingerprints would
+			ks.base = append(ks.base, fingerprint(key.of(l)))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		logErr("[!] reading %s: %v", s.f.Name(), err)
+	}
+	slices.Sort(ks.base)
+	ks.fps = fpSet{}
+	for _, p := range s.pend {
+		ks.add(key.of(p.text))
+	}
+	s.sets[key.name] = ks
+	return ks
+}
+
+// remember adds l, now in the file, to every key set but the one named
+// skip, which has it already.
+func (s *outputSink) remember(l, skip string) {
+	for name, ks := range s.sets {
+		if name != skip {
+			ks.add(ks.key.of(l))
+		}
+	}
+}
+
+func (s *outputSink) queue(p pendingLine) {
+	s.pend = append(s.pend, p)
+	s.size += len(p.text) + 1
+	if s.size >= sinkBatch {
+		s.writeBatch()
+	}
+}
+
+// writeBatch appends the pending lines, except those another process
+// wrote meanwhile, in a single write under the file lock.
+func (s *outputSink) writeBatch() error {
+	if len(s.pend) == 0 {
+		return nil
+	}
+	lockFile(s.f)
+	defer func() { unlockFile(s.f) }() // s.f changes when the file is rotated
+	// lines by their text and by their key in each set
+	theirs := map[string]bool{}
+	for _, l := range s.readSince() {
+		theirs["\x00"+l] = true
+		for name, ks := range s.sets {
+			k := ks.key.of(l)
+			ks.add(k)
+			theirs[name+"\x00"+k] = true
+		}
+	}
+	var b bytes.Buffer
+	for _, p := range s.pend {
+		if theirs["\x00"+p.text] || theirs[p.set+"\x00"+p.key] {
+			continue
+		}
+		b.WriteString(stamp(p.text, s.opts.stamp))
+		b.WriteByte('\n')
+	}
+	s.pend, s.size = s.pend[:0], 0
+	if s.rotateDue(b.Len()) {
+		s.rotateFile()
+	}
+	n, err := s.f.Write(b.Bytes())
+	s.off += int64(n)
+	if err != nil {
+		err = fmt.Errorf("cannot write output file: %w", err)
+		if s.opts.fallback {
+			// the lines the file didn't take
+			logErr("[!] %v; printing the rest to stdout", err)
+			os.Stdout.Write(b.Bytes()[n:])
+			return nil
+		}
+		failOutput(err)
+	}
+	return err
+}
+
+// outputErr is the first output file failure; the run stops and exits
+// with exitFatal once it is set.
+var outputErr atomic.Pointer[error]
+
+func failOutput(err error) {
+	if outputErr.CompareAndSwap(nil, &err) {
+		logErr("[!] %v", err)
+	}
+}
+
+// outputFailed returns the output failure, if any.
+func outputFailed() error {
+	if p := outputErr.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// stamp puts the current time (RFC 3339) and a tab in front of l when on,
+// for -timestamps. Deduplication looks past it.
+func stamp(l string, on bool) string {
+	if !on {
+		return l
+	}
+	return time.Now().Format(time.RFC3339) + "\t" + l
+}
+
+// unstamp strips a -timestamps prefix from l.
+func unstamp(l string) string {
+	if ts, rest, ok := strings.Cut(l, "\t"); ok {
+		if _, err := time.Parse(time.RFC3339, ts); err == nil {
+			return rest
+		}
+	}
+	return l
+}
+
+// stdoutSeen holds the fingerprints of lines already printed to stdout
+// under -fast-dedupe.
+var stdoutSeen = &struct {
+	sync.Mutex
+	fpSet
+}{fpSet: fpSet{}}
+
+type fpSet map[uint64]struct{}
+
+// fingerprint is the 64-bit hash -flush-every and -fast-dedupe keep
+// instead of the lines themselves. A collision, which drops one result,
+// has odds of roughly 1 in 30 million over a million results, and about 1
+// in 1.5 million over 5 million.
+func fingerprint(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// add reports whether v was new.
+func (f fpSet) add(v string) bool {
+	k := fingerprint(v)
+	if _, ok := f[k]; ok {
+		return false
+	}
+	f[k] = struct{}{}
+	return true
+}
+
+// filter returns the lines whose key is new, recording them.
+func (f fpSet) filter(lines []string, key keyFunc) []string {
+	out := lines[:0:0]
+	for _, l := range lines {
+		if f.add(key.of(l)) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// flush writes buffered lines to disk.
+func (s *outputSink) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeBatch()
+}
+
+// flushOutput writes the buffered lines of path, if it is open.
+func flushOutput(path string) {
+	sinks.Lock()
+	s := sinks.m[path]
+	sinks.Unlock()
+	if s != nil {
+		s.flush()
+	}
+}
+
+// closeOutput flushes and closes path, if it is open.
+func closeOutput(path string) {
+	sinks.Lock()
+	s := sinks.m[path]
+	delete(sinks.m, path)
+	sinks.Unlock()
+	if s == nil {
+		return
+	}
+	s.flush()
+	if err := s.f.Close(); err != nil {
+		failOutput(fmt.Errorf("cannot close output file: %w", err))
+	}
+}
+
+// closeOutputs flushes and closes every open output file. It is called on
+// normal exit and before exiting on cancellation.
+func closeOutputs() {
+	sinks.Lock()
+	defer sinks.Unlock()
+	for path, s := range sinks.m {
+		s.flush()
+		if err := s.f.Close(); err != nil {
+			failOutput(fmt.Errorf("cannot close output file: %w", err))
+		}
+		delete(sinks.m, path)
+	}
+}
+
+// Exit codes. exitTimeout is used when -max-runtime runs out.
+const (
+	exitOK            = 0   // results found
+	exitFatal         = 1   // bad arguments, unreadable input, ...
+	exitNoResults     = 2   // completed, nothing found
+	exitKeysExhausted = 3   // stopped because every API key ran out
+	exitTimeout       = 124 // -max-runtime reached
+	exitInterrupted   = 130 // Ctrl+C / SIGTERM
+)
+
+// keysRanOut is set when a run stopped for lack of usable API keys.
+var keysRanOut atomic.Bool
+
+// projectExhausted is set once a Cloud project's quota ran out for every
+// key thought to be in it.
+var projectExhausted atomic.Bool
+
+// exit flushes output files and terminates with code.
+func exit(code int) {
+	clearStatus()
+	closeOutputs()
+	if outputFailed() != nil && code != exitInterrupted && code != exitTimeout {
+		code = exitFatal
+	}
+	resumeRun.close()
+	auditRun.close()
+	lg.close()
+	osExit(code)
+}
+
+// osExit is os.Exit, replaced by tests.
+var osExit = os.Exit
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// A file keeps a key set for each key it is written under: a writer
+// comparing by host never decides what a writer comparing whole lines
+// finds, whichever writes first.
+func TestSinkKeys(t *testing.T) {
+	byHost := keyFunc{"counted-host", countedHostKey}
+	lower := keyFunc{"lower", strings.ToLower}
+	for _, compact := range []bool{false, true} {
+		name := "sets"
+		if compact {
+			name = "fingerprints"
+		}
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.txt")
+			if err := os.WriteFile(path, []byte("1\tdev.example.com\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(closeOutputs)
+			s, err := openSink(path, outputOptions{results: path, compact: compact})
+			if err != nil {
+				t.Fatal(err)
+			}
+			steps := []struct {
+				key   keyFunc
+				lines []string
+				want  []string
+			}{
+				{byHost, []string{"2\tdev.example.com", "1\tshop.example.com"}, []string{"1\tshop.example.com"}},
+				// still pending, but known to a key set built now
+				{lower, []string{"1\tSHOP.example.com", "2\tdev.example.com", "1\tDev.Example.com"}, []string{"2\tdev.example.com"}},
+				{exactKey, []string{"2\tdev.example.com", "3\tdev.example.com"}, []string{"3\tdev.example.com"}},
+				{byHost, []string{"4\tdev.example.com", "1\tapi.example.com"}, []string{"1\tapi.example.com"}},
+			}
+			for i, st := range steps {
+				if got := s.writeNew(st.lines, st.key); !slices.Equal(got, st.want) {
+					t.Errorf("step %d: writeNew = %q, want %q", i, got, st.want)
+				}
+			}
+			flushOutput(path)
+			b, _ := os.ReadFile(path)
+			want := "1\tdev.example.com\n1\tshop.example.com\n2\tdev.example.com\n3\tdev.example.com\n1\tapi.example.com\n"
+			if string(b) != want {
+				t.Errorf("file = %q, want %q", b, want)
+			}
+		})
+	}
+}
+
+// Lines another process appended between batches are not written again,
+// under the key of the pending line.
+func TestSinkOtherWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	t.Cleanup(closeOutputs)
+	s, err := openSink(path, outputOptions{results: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byHost := keyFunc{"counted-host", countedHostKey}
+	s.writeNew([]string{"1\tdev.example.com"}, byHost)
+	flushOutput(path)
+	s.writeNew([]string{"1\tapi.example.com", "1\tshop.example.com"}, byHost)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("7\tshop.example.com\n")
+	f.Close()
+	flushOutput(path)
+	b, _ := os.ReadFile(path)
+	want := "1\tdev.example.com\n7\tshop.example.com\n1\tapi.example.com\n"
+	if string(b) != want {
+		t.Errorf("file = %q, want %q", b, want)
+	}
+	if got := s.writeNew([]string{"9\tshop.example.com"}, byHost); got != nil {
+		t.Errorf("writeNew = %q, want nothing: shop.example.com is in the file", got)
+	}
+}
+
+// BenchmarkKeySet loads the key set of a 5M-line -o file, as the first
+// write to it does, with the lines themselves in a map and with the
+// fingerprints of -fast-dedupe. heap-MB is what the set keeps.
+func BenchmarkKeySet(b *testing.B) {
+	const lines = 5_000_000
+	path := filepath.Join(b.TempDir(), "out.txt")
+	f, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	for i := range lines {
+		fmt.Fprintf(w, "https://sub%d.example.com/path/to/page-%d?id=%d\n", i%1000, i, i)
+	}
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	f.Close()
+
+	for _, compact := range []bool{false, true} {
+		name := "map"
+		if compact {
+			name = "fingerprints"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			var heap uint64
+			for range b.N {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				s, err := openSink(path, outputOptions{results: path, compact: compact})
+				if err != nil {
+					b.Fatal(err)
+				}
+				ks := s.keySet(exactKey)
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				heap += after.HeapAlloc - before.HeapAlloc
+				known := !ks.add("https://sub0.example.com/path/to/page-0?id=0")
+				closeOutputs()
+				if !known {
+					b.Fatal("a line of the file counted as new")
+				}
+			}
+			b.ReportMetric(float64(heap)/float64(b.N)/(1<<20), "heap-MB")
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// --- Subdomain permutations (-permute) ---
+
+// permuteCandidates combines the first label of every known host with each
+// word (dev + api -> dev-api, api-dev, devapi, api, dev2, ...) under the
+// same parent domain, skipping known hosts, up to max candidates.
+func permuteCandidates(known []string, words []string, apex string, max int) []string {
+	isKnown := make(map[string]bool, len(known))
+	for _, h := range known {
+		isKnown[h] = true
+	}
+	var out []string
+	seen := map[string]bool{}
+	add := func(label, parent string) bool {
+		h := strings.Trim(strings.ToLower(label), "-.") + "." + parent
+		if strings.HasPrefix(h, ".") || isKnown[h] || seen[h] {
+			return true
+		}
+		seen[h] = true
+		out = append(out, h)
+		return max <= 0 || len(out) < max
+	}
+	for _, h := range known {
+		if h == apex || !strings.HasSuffix(h, "."+apex) {
+			continue
+		}
+		label, parent, _ := strings.Cut(h, ".")
+		for n := 1; n <= 3; n++ {
+			if !add(fmt.Sprintf("%s%d", label, n), parent) {
+				return out
+			}
+		}
+		for _, w := range words {
+			for _, l := range []string{w, label + "-" + w, w + "-" + label, label + w} {
+				if !add(l, parent) {
+					return out
+				}
+			}
+		}
+	}
+	return out
+}
+
+// permuteHosts generates candidates from -permute and returns those that
+// exist, checked by DNS or by a one-page site: query each
+// (-permute-verify).
+func (c *Config) permuteHosts(ctx context.Context, known []string, apex string) []string {
+	words := readListFile(c.permuteFile)
+	cands := permuteCandidates(known, words, apex, c.permuteMax)
+	if len(cands) == 0 {
+		return nil
+	}
+	logv(c.verbose, "Checking %d permutations", len(cands))
+	var found []string
+	if c.permuteVerify == "search" {
+		for _, h := range cands {
+			if ctx.Err() != nil {
+				break
+			}
+			c2 := *c
+			c2.target = h
+			c2.subdomainMode, c2.includeSubdomains = false, false
+			c2.pages = 1
+			c2.track = nil
+			// plain site:candidate, whatever else the run searches for
+			c2.dork, c2.dictionary, c2.contents = "", "", ""
+			for _, r := range c2.dorkRun(ctx, "") {
+				if banshee.HostOf(r.url) == h {
+					found = append(found, h)
+					break
+				}
+			}
+		}
+		return found
+	}
+	for _, rh := range c.resolveHosts(ctx, cands) {
+		if !rh.unverified {
+			found = append(found, rh.host)
+		}
+	}
+	return found
+}
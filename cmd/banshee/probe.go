@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Probing result URLs ---
+
+type probeResult struct {
+	url    string
+	status int
+	length int64
+	title  string
+	err    error
+}
+
+var titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// probeURLs fetches every URL with a bounded worker pool and returns them
+// annotated as "url [status] [length] [title]". Failed fetches are kept as
+// "url [failed]", or returned as "" when -probe-alive-only is set. The output
+// lines up index for index with the input.
+func (c *Config) probeURLs(ctx context.Context, urls []string) []string {
+	workers := c.probeWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	// Reuse proxy/transport settings but report redirects instead of following them
+	cl := *c.client
+	cl.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	results := make([]*probeResult, len(urls))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r := probeOne(ctx, &cl, urls[i])
+				results[i] = &r
+			}
+		}()
+	}
+feed:
+	for i := range urls {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	out := make([]string, len(urls))
+	for i, r := range results {
+		switch {
+		case r == nil:
+			// not probed (cancelled); keep the plain URL
+			out[i] = urls[i]
+		case r.err != nil:
+			logv(c.verbose, "Probe failed: %s (%v)", r.url, r.err)
+			if !c.probeAliveOnly {
+				out[i] = r.url + " [failed]"
+			}
+		default:
+			out[i] = fmt.Sprintf("%s [%d] [%d] [%s]", r.url, r.status, r.length, r.title)
+		}
+	}
+	return out
+}
+
+func probeOne(ctx context.Context, cl *http.Client, u string) probeResult {
+	pr := probeResult{url: u}
+	rctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(rctx, http.MethodGet, u, nil)
+	if err != nil {
+		pr.err = err
+		return pr
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	resp, err := cl.Do(req)
+	if err != nil {
+		pr.err = err
+		return pr
+	}
+	defer resp.Body.Close()
+	// Only the head of the body is needed for the title
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	pr.status = resp.StatusCode
+	pr.length = resp.ContentLength
+	if pr.length < 0 {
+		pr.length = int64(len(body))
+	}
+	if m := titleRe.FindSubmatch(body); m != nil {
+		pr.title = strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+	}
+	return pr
+}
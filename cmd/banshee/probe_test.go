@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// Probing annotates what is shown, not what is saved: -o stays a list of
+// URLs, deduplicated by URL however the page changes between probes.
+func TestProbeOutputKeepsURLs(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<html><title>visit %d</title></html>", hits.Add(1))
+	}))
+	defer srv.Close()
+
+	cfg := parseFlags(t, "-u", "127.0.0.1", "-probe")
+	cfg.client = srv.Client()
+	read := useOutput(t, cfg)
+	info := captureInfo(t)
+
+	u := srv.URL + "/admin"
+	for range 2 {
+		cfg.emit(context.Background(), []result{{url: u, term: "admin"}})
+	}
+	if got := read(); len(got) != 1 || got[0] != u {
+		t.Errorf("-o holds %q, want just %s", got, u)
+	}
+	shown := info.String()
+	for _, want := range []string{u + " [200]", "[visit 1]", "[visit 2]"} {
+		if !strings.Contains(shown, want) {
+			t.Errorf("annotations shown = %q, want them to contain %q", shown, want)
+		}
+	}
+}
+
+// Deduplicating by the URL before the annotations must not fold the
+// "# term" headers of -group-by-term into one.
+func TestGroupByTermHeaders(t *testing.T) {
+	cfg := parseFlags(t, "-u", "example.com", "-group-by-term")
+	read := useOutput(t, cfg)
+	cfg.emit(context.Background(), []result{
+		{url: "https://example.com/a", term: "admin"},
+		{url: "https://example.com/b", term: "login"},
+	})
+	want := []string{"# admin", "https://example.com/a", "# login", "https://example.com/b"}
+	if got := read(); !slices.Equal(got, want) {
+		t.Errorf("-o holds %q, want %q", got, want)
+	}
+}
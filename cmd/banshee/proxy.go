@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// --- Proxy selection ---
+
+// -proxy-mode values.
+const (
+	proxyFlag   = "flag"   // -proxy for everything when set, else the environment
+	proxyEnv    = "env"    // the environment only, -proxy is ignored
+	proxyMerged = "merged" // -proxy, except for hosts NO_PROXY excludes
+)
+
+// proxyFunc returns the transport's proxy selection for -proxy and
+// -proxy-mode.
+func proxyFunc(proxyURL, mode string) (func(*http.Request) (*url.URL, error), error) {
+	var u *url.URL
+	if proxyURL != "" {
+		var err error
+		if u, err = url.Parse(proxyURL); err != nil {
+			return nil, err
+		}
+	}
+	switch mode {
+	case proxyEnv:
+		return http.ProxyFromEnvironment, nil
+	case proxyFlag, proxyMerged:
+	default:
+		return nil, fmt.Errorf("unknown -proxy-mode %q (want flag, env or merged)", mode)
+	}
+	if u == nil {
+		return http.ProxyFromEnvironment, nil
+	}
+	if mode == proxyFlag {
+		return http.ProxyURL(u), nil
+	}
+	pc := httpproxy.Config{HTTPProxy: proxyURL, HTTPSProxy: proxyURL, NoProxy: httpproxy.FromEnvironment().NoProxy}
+	f := pc.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) { return f(req.URL) }, nil
+}
+
+// logProxy says at startup which proxy, if any, the first engine's
+// requests go through and why, whenever -proxy or a proxy environment
+// variable is set. -proxy silently winning over HTTPS_PROXY, or NO_PROXY
+// silently losing to -proxy, is otherwise easy to miss.
+func (c *Config) logProxy() {
+	env := httpproxy.FromEnvironment()
+	if c.proxy == "" && env.HTTPProxy == "" && env.HTTPSProxy == "" {
+		return
+	}
+	endpoint := banshee.EndpointURL(c.engine)
+	tr, ok := c.client.Transport.(*http.Transport)
+	if endpoint == "" || !ok || tr.Proxy == nil {
+		return
+	}
+	req, err := http.NewRequest(http.MethodHead, endpoint, nil)
+	if err != nil {
+		return
+	}
+	via := "direct"
+	if u, err := tr.Proxy(req); err != nil {
+		via = "error: " + err.Error()
+	} else if u != nil {
+		via = "via " + u.Redacted()
+	}
+	var why string
+	switch {
+	case c.proxyMode == proxyEnv && c.proxy != "":
+		why = "environment; -proxy ignored"
+	case c.proxyMode == proxyEnv || c.proxy == "":
+		why = "environment"
+	case c.proxyMode == proxyMerged:
+		why = "-proxy, NO_PROXY honoured"
+	case env.HTTPProxy != "" || env.HTTPSProxy != "" || env.NoProxy != "":
+		why = "-proxy; proxy environment variables ignored"
+	default:
+		why = "-proxy"
+	}
+	msg := fmt.Sprintf("[*] %s requests go %s (-proxy-mode %s: %s)", req.URL.Host, via, c.proxyMode, why)
+	consoleWrite(os.Stderr, msg)
+	lg.event("proxy", "host", req.URL.Host, "proxy", via, "mode", c.proxyMode)
+}
+
+// --- Proxy preflight ---
+
+// proxyCheckTimeout bounds the preflight request.
+const proxyCheckTimeout = 10 * time.Second
+
+// checkProxy sends one HEAD request to the first engine's API through the
+// proxy the client would use for it, so a dead -proxy fails the run up
+// front instead of showing up as an error on every query. Any HTTP answer
+// from the API, whatever its status, passes. Without a proxy it does
+// nothing.
+func (c *Config) checkProxy(ctx context.Context) error {
+	endpoint := banshee.EndpointURL(c.engine)
+	tr, ok := c.client.Transport.(*http.Transport)
+	if endpoint == "" || !ok || tr.Proxy == nil {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodHead, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	proxy, err := tr.Proxy(req)
+	if err != nil || proxy == nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, proxyCheckTimeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", banshee.DefaultUserAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return proxyError(proxy, req.URL.Host, err)
+	}
+	resp.Body.Close()
+	logv(c.verbose, "Proxy %s reaches %s (status %d)", proxy.Redacted(), req.URL.Host, resp.StatusCode)
+	return nil
+}
+
+// proxyError words a failed preflight request by what went wrong: the
+// proxy itself, or the proxy's attempt to reach host.
+func proxyError(proxy *url.URL, host string, err error) error {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || isNetTimeout(err):
+		return fmt.Errorf("proxy unreachable: no answer from %s within %s", proxy.Host, proxyCheckTimeout)
+	case errors.As(err, &dnsErr) && dnsErr.Name == proxy.Hostname():
+		return fmt.Errorf("proxy unreachable: cannot resolve %s", proxy.Hostname())
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Errorf("proxy unreachable: connection refused to %s", proxy.Host)
+	case errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect"):
+		return fmt.Errorf("proxy unreachable: %s: %v", proxy.Host, opErr.Err)
+	}
+	// the proxy answered, but couldn't or wouldn't take us to the API
+	// (407, a refused CONNECT, a broken TLS tunnel...)
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		err = ue.Err
+	}
+	return fmt.Errorf("proxy %s failed to reach %s: %v", proxy.Host, host, err)
+}
+
+func isNetTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// --- Recursive subdomain discovery (-recursion) ---
+
+// recurseSubdomains runs the -recursion rounds of subdomain mode. Round 2
+// searches site:*.host for every host below the target found in round 1,
+// round 3 does the same for the hosts new in round 2, and so on. Each host
+// is searched at most once, and the rounds stop once they have spent
+// -recursion-max-requests API requests.
+func (c *Config) recurseSubdomains(ctx context.Context, depth map[string]int, add func(host, source string, depth int)) {
+	start := c.quotaState().Requests
+	searched := map[string]bool{c.target: true}
+	for round := 2; round <= c.recursion+1; round++ {
+		var next []string
+		for h, d := range depth {
+			if d == round-1 && !searched[h] {
+				next = append(next, h)
+			}
+		}
+		if len(next) == 0 {
+			return
+		}
+		sort.Strings(next)
+		logv(c.verbose, "Recursion depth %d: searching under %d hosts", round, len(next))
+		for _, h := range next {
+			if ctx.Err() != nil {
+				return
+			}
+			if spent := c.quotaState().Requests - start; c.recursionMaxReqs > 0 && spent >= c.recursionMaxReqs {
+				logErr("[!] %s: -recursion-max-requests %d reached at depth %d", c.target, c.recursionMaxReqs, round)
+				return
+			}
+			searched[h] = true
+			c2 := *c
+			c2.target = h
+			c2.wildcard = true
+			c2.includeSubdomains = false
+			c2.track = nil
+			c2.noResultsFile = ""
+			for _, r := range c2.dorkRun(ctx, "") {
+				add(banshee.HostOf(r.url), r.engine, round)
+			}
+		}
+	}
+}
+
+// jsonHost is a subdomain mode result under -json.
+type jsonHost struct {
+	Host    string   `json:"host"`
+	Target  string   `json:"target,omitempty"`
+	Depth   int      `json:"depth"`
+	Sources []string `json:"sources,omitempty"`
+	IPs     []string `json:"ips,omitempty"`
+	// Unverified marks a -resolve'd host whose lookup failed for a reason
+	// other than NXDOMAIN, or was cut short.
+	Unverified bool `json:"unverified,omitempty"`
+}
+
+// hostsJSON renders hosts as JSON lines. resolved, when -resolve ran,
+// supplies the addresses.
+func (c *Config) hostsJSON(hosts []string, sources map[string][]string, depth map[string]int, resolved []resolvedHost) []string {
+	byHost := map[string]resolvedHost{}
+	for _, r := range resolved {
+		byHost[r.host] = r
+	}
+	out := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		r := byHost[h]
+		b, _ := json.Marshal(jsonHost{Host: h, Target: c.target, Depth: depth[h], Sources: sources[h], IPs: r.ips, Unverified: r.unverified})
+		out = append(out, string(b))
+	}
+	return out
+}
+
+// jsonHostKey dedupes jsonHost lines by host.
+func jsonHostKey(l string) string {
+	var jh jsonHost
+	if json.Unmarshal([]byte(l), &jh) != nil {
+		return l
+	}
+	return jh.Host
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// --- Key reload (SIGHUP) ---
+
+// watchKeyReload reloads the API keys on every SIGHUP for the rest of the
+// run. It needs the providers loaded.
+func (c *Config) watchKeyReload() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			c.reloadKeys()
+		}
+	}()
+}
+
+// reloadKeys reads each engine's keys again, looking where loadProviders
+// did (-k, $BANSHEE_KEYS, then the config directories), and swaps them
+// into its pool. Keys that stay keep their quota state; an engine whose
+// keys can't be read keeps the ones it has.
+func (c *Config) reloadKeys() {
+	for i, e := range c.engines {
+		r, ok := c.providers[e].(banshee.KeyReloader)
+		if !ok {
+			continue
+		}
+		keys, path, err := loadAPIKeys(e, c.keysFile, i == 0)
+		if err != nil {
+			logErr("[!] SIGHUP: %s keys not reloaded: %v", e, err)
+			continue
+		}
+		added, removed := r.ReloadKeys(keys)
+		logInfo("[*] SIGHUP: reloaded %s keys from %s: %d added, %d removed, %d in use",
+			e, path, added, removed, c.providers[e].QuotaState().Keys)
+		lg.event("keys_reloaded", "engine", e, "path", path, "added", added, "removed", removed)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// --- DNS resolution of discovered subdomains ---
+
+type resolvedHost struct {
+	host       string
+	ips        []string
+	unverified bool // lookup failed for a reason other than NXDOMAIN
+}
+
+// newResolver returns the system resolver, or one that sends every query to
+// server when -dns is set.
+func newResolver(server string) *net.Resolver {
+	if server == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: 5 * time.Second}
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// resolveHosts looks up hosts with a bounded worker pool and drops the ones
+// that return NXDOMAIN. Timeouts, SERVFAIL and similar failures keep the host
+// but mark it unverified, as do lookups cut short or never made because ctx
+// ended. Order of the input is preserved.
+func (c *Config) resolveHosts(ctx context.Context, hosts []string) []resolvedHost {
+	workers := c.resolveWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	r := newResolver(c.dnsServer)
+	out := make([]*resolvedHost, len(hosts))
+	for i, h := range hosts {
+		out[i] = &resolvedHost{host: h, unverified: true}
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				lctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+				ips, err := r.LookupHost(lctx, hosts[i])
+				cancel()
+				var dnsErr *net.DNSError
+				switch {
+				case err == nil:
+					out[i] = &resolvedHost{host: hosts[i], ips: ips}
+				case ctx.Err() != nil:
+					// cancelled, not an answer: left unverified
+				case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+					logv(c.verbose, "NXDOMAIN: %s", hosts[i])
+					out[i] = nil
+				default:
+					logv(c.verbose, "Unverified: %s (%v)", hosts[i], err)
+				}
+			}
+		}()
+	}
+feed:
+	for i := range hosts {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	res := make([]resolvedHost, 0, len(hosts))
+	for _, rh := range out {
+		if rh != nil {
+			res = append(res, *rh)
+		}
+	}
+	return res
+}
+
+// formatResolved renders resolved hosts as output lines, honoring -relative
+// and -show-ips. Hosts that could not be verified are always marked.
+func (c *Config) formatResolved(hosts []resolvedHost, apex string) []string {
+	lines := make([]string, 0, len(hosts))
+	for _, rh := range hosts {
+		h := rh.host
+		if c.relativeHosts {
+			h = banshee.RelativeHost(h, apex)
+		}
+		switch {
+		case rh.unverified:
+			h += " [unverified]"
+		case c.showIPs:
+			h += " " + strings.Join(rh.ips, ",")
+		}
+		lines = append(lines, h)
+	}
+	return lines
+}
+
+// resolvedHostKey compares -resolve output lines by host, so that a host
+// written before is not added again with other addresses or marked
+// unverified. A -label-terms prefix is part of the line, not the host.
+func resolvedHostKey(l string) string {
+	if _, h, ok := strings.Cut(l, "\t"); ok {
+		l = h
+	}
+	h, _, _ := strings.Cut(l, " ")
+	return h
+}
+
+// Subdomain output formats for -format, besides the default (one host per
+// line, decorated by -relative, -show-ips and -label-terms).
+const (
+	formatMassdns = "massdns" // bare hostnames, nothing else
+	formatHosts   = "hosts"   // "IP hostname" lines for /etc/hosts
+)
+
+// checkHostFormat validates -format and -hosts-ip. -format hosts turns
+// -resolve on, as it needs the addresses.
+func (cfg *Config) checkHostFormat() error {
+	switch cfg.hostsIP {
+	case "4", "6", "both":
+	default:
+		return fmt.Errorf("unknown -hosts-ip %q (want 4, 6 or both)", cfg.hostsIP)
+	}
+	switch cfg.hostFormat {
+	case "":
+		return nil
+	case formatMassdns, formatHosts:
+	default:
+		return fmt.Errorf("unknown -format %q (want massdns or hosts)", cfg.hostFormat)
+	}
+	if !cfg.subdomainMode && !slices.Contains(cfg.modeList, "subs") {
+		return fmt.Errorf("-format %s needs -s", cfg.hostFormat)
+	}
+	for _, f := range []struct {
+		on   bool
+		name string
+	}{{cfg.jsonOutput, "-json"}, {cfg.relativeHosts, "-relative"}, {cfg.showIPs, "-show-ips"}, {cfg.labelTerms, "-label-terms"}} {
+		if f.on {
+			return fmt.Errorf("-format and %s are exclusive", f.name)
+		}
+	}
+	if cfg.hostFormat == formatHosts {
+		cfg.resolve = true
+	}
+	return nil
+}
+
+// massdnsLines returns hosts as bare hostnames, without a scheme, port,
+// wildcard label or trailing dot, for massdns and similar resolvers. IP
+// addresses are left out.
+func massdnsLines(hosts []string) []string {
+	lines := make([]string, 0, len(hosts))
+	seen := map[string]bool{}
+	for _, h := range hosts {
+		h = strings.TrimPrefix(banshee.HostOf(h), "*.")
+		if h == "" || net.ParseIP(h) != nil || seen[h] {
+			continue
+		}
+		seen[h] = true
+		lines = append(lines, h)
+	}
+	return lines
+}
+
+// hostsLines renders resolved hosts as hosts file entries. Hosts whose
+// lookup failed have no address and are left out. -hosts-ip picks the
+// address: the first IPv4 one (4) or IPv6 one (6), falling back to the
+// other family, or every address (both), one line each.
+func (c *Config) hostsLines(hosts []resolvedHost) []string {
+	var lines []string
+	for _, rh := range hosts {
+		var v4, v6 []string
+		for _, ip := range rh.ips {
+			if p := net.ParseIP(ip); p == nil {
+				continue
+			} else if p.To4() != nil {
+				v4 = append(v4, ip)
+			} else {
+				v6 = append(v6, ip)
+			}
+		}
+		ips := append(v4, v6...)
+		if c.hostsIP == "6" {
+			ips = append(v6, v4...)
+		}
+		if c.hostsIP != "both" && len(ips) > 1 {
+			ips = ips[:1]
+		}
+		for _, ip := range ips {
+			lines = append(lines, ip+" "+rh.host)
+		}
+	}
+	return lines
+}
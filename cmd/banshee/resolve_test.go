@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// Lookups that ctx cut short say nothing about the host: it is kept as
+// unverified rather than dropped like an NXDOMAIN.
+func TestResolveHostsCancelled(t *testing.T) {
+	cfg := parseFlags(t, "-resolve", "-dns", "127.0.0.1:1")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hosts := []string{"a.example.com", "b.example.com", "c.example.com"}
+	got := cfg.resolveHosts(ctx, hosts)
+	if len(got) != len(hosts) {
+		t.Fatalf("got %d hosts, want %d", len(got), len(hosts))
+	}
+	for i, rh := range got {
+		if rh.host != hosts[i] || !rh.unverified || rh.ips != nil {
+			t.Errorf("host %d = %+v, want %s unverified", i, rh, hosts[i])
+		}
+	}
+}
+
+func TestFormatResolved(t *testing.T) {
+	hosts := []resolvedHost{
+		{host: "dev.example.com", ips: []string{"203.0.113.7", "2001:db8::7"}},
+		{host: "old.example.com", unverified: true},
+	}
+	tests := []struct {
+		name  string
+		flags []string
+		want  []string
+	}{
+		{"plain", nil, []string{"dev.example.com", "old.example.com [unverified]"}},
+		{"show-ips", []string{"-show-ips"}, []string{"dev.example.com 203.0.113.7,2001:db8::7", "old.example.com [unverified]"}},
+		{"relative", []string{"-relative"}, []string{"dev", "old [unverified]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parseFlags(t, tt.flags...)
+			if got := cfg.formatResolved(hosts, "example.com"); !slices.Equal(got, tt.want) {
+				t.Errorf("formatResolved = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvedHostKey(t *testing.T) {
+	for _, l := range []string{
+		"dev.example.com",
+		"dev.example.com [unverified]",
+		"dev.example.com 203.0.113.7",
+		"crtsh,google\tdev.example.com 203.0.113.7",
+	} {
+		if k := resolvedHostKey(l); k != "dev.example.com" {
+			t.Errorf("resolvedHostKey(%q) = %q, want dev.example.com", l, k)
+		}
+	}
+}
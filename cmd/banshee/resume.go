@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// --- Resuming -f runs (-resume) ---
+
+// resumeState records the targets of a -f run that completed, one JSON
+// line each after a header line holding the hash of the run's options.
+type resumeState struct {
+	mu   sync.Mutex
+	f    *os.File
+	done map[string]bool
+}
+
+type resumeLine struct {
+	Options string `json:"options,omitempty"`
+	Done    string `json:"done,omitempty"`
+}
+
+var resumeRun *resumeState
+
+// resumeIgnored are the flags that don't change what a run finds, so they
+// may differ between a run and its resumption.
+var resumeIgnored = map[string]bool{
+	"resume": true, "v": true, "verbose": true, "silent": true,
+	"log-file": true, "log-format": true, "skipped-file": true, "no-results-file": true,
+	"domain-timeout": true, "max-runtime": true, "audit": true,
+	"no-color": true, "debug": true, "k": true, "keys": true, "dl": true, "skip": true, "shuffle": true,
+	"skip-proxy-check": true, "manifest": true, "rotate": true,
+}
+
+// optionsHash hashes the flags set on the command line, except
+// resumeIgnored ones.
+func optionsHash(fs *flag.FlagSet) string {
+	var set []string
+	fs.Visit(func(f *flag.Flag) {
+		if !resumeIgnored[f.Name] {
+			set = append(set, f.Name+"="+f.Value.String())
+		}
+	})
+	sort.Strings(set)
+	h := sha256.Sum256([]byte(strings.Join(set, "\n")))
+	return hex.EncodeToString(h[:8])
+}
+
+// openResume loads path, warning when it was written with other options,
+// and keeps it open to record targets as they complete.
+func openResume(path, hash string) (*resumeState, error) {
+	r := &resumeState{done: map[string]bool{}}
+	header := ""
+	if f, err := os.Open(path); err == nil {
+		sc := newLineScanner(f)
+		for sc.Scan() {
+			var l resumeLine
+			if json.Unmarshal(sc.Bytes(), &l) != nil {
+				continue // a line cut short by a crash
+			}
+			if l.Options != "" && header == "" {
+				header = l.Options
+			}
+			if l.Done != "" {
+				r.done[l.Done] = true
+			}
+		}
+		err := sc.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	r.f = f
+	switch {
+	case header == "":
+		r.write(resumeLine{Options: hash})
+	case header != hash:
+		logErr("[!] WARNING: -resume %s was written by a run with different options; %d targets it completed will be skipped anyway", path, len(r.done))
+	}
+	return r, nil
+}
+
+func (r *resumeState) write(l resumeLine) {
+	if r.f == nil {
+		return
+	}
+	b, _ := json.Marshal(l)
+	if _, err := r.f.Write(append(b, '\n')); err != nil {
+		logErr("[!] cannot write resume file: %v", err)
+	}
+}
+
+// completed reports whether target finished in an earlier run. A nil
+// state has completed nothing.
+func (r *resumeState) completed(target string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.done[target]
+}
+
+// markDone records that target finished.
+func (r *resumeState) markDone(target string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done[target] = true
+	r.write(resumeLine{Done: target})
+}
+
+func (r *resumeState) close() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return
+	}
+	if err := r.f.Sync(); err != nil {
+		logErr("[!] cannot write resume file: %v", err)
+	}
+	r.f.Close()
+	r.f = nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Output rotation (-rotate) ---
+
+// rotatePolicy says when the -o file is set aside for a fresh one: once it
+// would grow past size bytes, or once a day. The zero policy never rotates.
+type rotatePolicy struct {
+	size  int64
+	daily bool
+}
+
+// parseRotate parses -rotate: "daily" or "size=N" with an optional KB, MB
+// or GB suffix (powers of 1024).
+func parseRotate(v string) (rotatePolicy, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return rotatePolicy{}, nil
+	}
+	if strings.EqualFold(v, "daily") {
+		return rotatePolicy{daily: true}, nil
+	}
+	num, ok := strings.CutPrefix(strings.ToLower(v), "size=")
+	if !ok {
+		return rotatePolicy{}, fmt.Errorf("invalid -rotate %q (want size=N[KB|MB|GB] or daily)", v)
+	}
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30}, {"k", 1 << 10}, {"m", 1 << 20}, {"g", 1 << 30}, {"b", 1}} {
+		if s, ok := strings.CutSuffix(num, u.suffix); ok {
+			num, mult = s, u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(num), 10, 64)
+	if err != nil || n <= 0 {
+		return rotatePolicy{}, fmt.Errorf("invalid -rotate size %q", v)
+	}
+	return rotatePolicy{size: n * mult}, nil
+}
+
+// rotateDue reports whether s must start a fresh file before appending
+// n more bytes. An empty file is never set aside.
+func (s *outputSink) rotateDue(n int) bool {
+	switch {
+	case !s.rotate || s.off == 0:
+		return false
+	case s.opts.rotation.daily:
+		return dayOf(time.Now()) != s.day
+	case s.opts.rotation.size > 0:
+		return s.off+int64(n) > s.opts.rotation.size
+	}
+	return false
+}
+
+// rotateFile renames the sink's file with a timestamp suffix, the day it
+// covers under daily rotation, and continues in a new file at the same
+// path. It is called with the file locked and leaves the new one locked.
+// What was already written is not lost on failure: the sink keeps
+// appending to the file it has.
+func (s *outputSink) rotateFile() {
+	path := s.f.Name()
+	suffix := time.Now().Format("20060102-150405")
+	if s.opts.rotation.daily {
+		suffix = s.day
+	}
+	dst := path + "." + suffix
+	for i := 1; fileExists(dst); i++ {
+		dst = fmt.Sprintf("%s.%s-%d", path, suffix, i)
+	}
+	if err := os.Rename(path, dst); err != nil {
+		logErr("[!] cannot rotate output file: %v", err)
+		return
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		logErr("[!] cannot rotate output file: %v; still writing to %s", err, dst)
+		return
+	}
+	lockFile(f)
+	unlockFile(s.f)
+	s.f.Close()
+	s.f, s.off, s.day = f, 0, dayOf(time.Now())
+	logv(true, "[*] %s rotated to %s", path, dst)
+}
+
+func dayOf(t time.Time) string {
+	return t.Format("2006-01-02")
+}
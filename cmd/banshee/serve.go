@@ -0,0 +1,559 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// --- serve: HTTP job API ---
+//
+// banshee serve runs searches submitted over HTTP. Every job gets its own
+// context and output file (JSON lines) in -jobs-dir, while the API keys,
+// HTTP client and request pacing are shared by all jobs.
+//
+//	POST   /jobs               {"targets": [...], "mode": "...", "value": "...", "options": {...}}
+//	GET    /jobs               list jobs
+//	GET    /jobs/{id}          status and progress
+//	GET    /jobs/{id}/results  results as JSON lines, streamed while the job runs
+//	DELETE /jobs/{id}          cancel, and forget the job
+//
+// Finished jobs are forgotten after -job-ttl; their results files stay.
+
+// jobModes maps a job mode to the flag that selects it on the command line.
+var jobModes = map[string]string{
+	"dork":       "q",
+	"extensions": "e",
+	"dictionary": "w",
+	"contents":   "c",
+	"subdomains": "s",
+}
+
+// jobOptions are the flags a job may set. Anything reading or writing local
+// files, or sending data elsewhere, stays under the server's control.
+var jobOptions = map[string]bool{
+	"pages": true, "exclusions": true, "recursive": true, "delay": true,
+	"min-delay": true, "max-delay": true, "abort-empty": true,
+	"concurrency": true, "workers": true, "raw-urls": true,
+	"filter-extensions": true, "no-static": true, "params-only": true,
+	"unique-params": true, "dedupe-loose": true, "strip-params": true,
+	"gf": true, "relative": true, "resolve": true, "show-ips": true,
+	"probe": true, "probe-alive-only": true, "sources": true,
+	"wayback-limit": true,
+}
+
+type jobRequest struct {
+	Targets []string       `json:"targets"`
+	Target  string         `json:"target"`
+	Mode    string         `json:"mode"`
+	Value   string         `json:"value"`
+	Options map[string]any `json:"options"`
+}
+
+type job struct {
+	mu       sync.Mutex
+	ID       string    `json:"id"`
+	Targets  []string  `json:"targets"`
+	Mode     string    `json:"mode"`
+	State    string    `json:"state"` // queued, running, done, cancelled, failed
+	Error    string    `json:"error,omitempty"`
+	Done     int       `json:"targets_done"`
+	Current  string    `json:"current,omitempty"`
+	Results  int       `json:"results"`
+	Created  time.Time `json:"created"`
+	Started  time.Time `json:"started,omitzero"`
+	Finished time.Time `json:"finished,omitzero"`
+
+	cfg      *Config
+	path     string
+	written  atomic.Int64 // results written so far
+	cancel   context.CancelFunc
+	finished chan struct{}
+}
+
+type server struct {
+	base  *Config
+	token string
+	dir   string
+	slots chan struct{} // bounds concurrently running jobs
+	ttl   time.Duration // how long finished jobs are kept
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// serveMain implements "banshee serve".
+func serveMain(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", ":8089", "Address to listen on")
+	token := fs.String("token", os.Getenv("BANSHEE_TOKEN"), "Bearer token required on every request (default $BANSHEE_TOKEN)")
+	dir := fs.String("jobs-dir", "", "Directory for job results (default a new temporary directory)")
+	maxJobs := fs.Int("max-jobs", 2, "Number of jobs run at the same time")
+	ttl := fs.Duration("job-ttl", 24*time.Hour, "How long finished jobs can be queried before they are forgotten")
+	rate := fs.Float64("rate", 1, "Most search requests per second across all jobs (0 = no limit)")
+	engine := fs.String("engine", "google", "Search backend(s) used by every job")
+	proxy := fs.String("proxy", "", "Specify an [protocol://]host[:port] proxy")
+	verbose := fs.Bool("v", false, "Enable verbose")
+	metricsListen := fs.String("metrics-listen", "", "Also serve /metrics on this address, without -token (default: only on -listen)")
+	fs.Parse(args)
+
+	if *token == "" && !loopbackAddr(*listen) {
+		logErr("[!] serve: -token (or $BANSHEE_TOKEN) is required unless -listen is a loopback address")
+		os.Exit(exitFatal)
+	}
+	if *maxJobs < 1 {
+		*maxJobs = 1
+	}
+	base := &Config{stats: &engineStats{}}
+	registerFlags(flag.NewFlagSet("defaults", flag.ContinueOnError), base)
+	base.verbose = *verbose
+	engines, err := parseEngines(*engine)
+	if err != nil {
+		logErr("[!] %v", err)
+		os.Exit(exitFatal)
+	}
+	base.engine, base.engines = *engine, engines
+	if base.client, err = buildHTTPClient(*proxy, proxyFlag); err != nil {
+		logErr("[!] Invalid proxy: %v", err)
+		os.Exit(exitFatal)
+	}
+	if err := base.loadProviders(); err != nil {
+		logErr("API keys file not found or unreadable: %v", err)
+		os.Exit(exitFatal)
+	}
+	if *rate > 0 {
+		base.pace = &pacer{interval: time.Duration(float64(time.Second) / *rate)}
+	}
+	if *dir == "" {
+		if *dir, err = os.MkdirTemp("", "banshee-jobs-"); err != nil {
+			logErr("[!] %v", err)
+			os.Exit(exitFatal)
+		}
+	} else if err := os.MkdirAll(*dir, 0o755); err != nil {
+		logErr("[!] %v", err)
+		os.Exit(exitFatal)
+	}
+
+	s := &server{base: base, token: *token, dir: *dir, slots: make(chan struct{}, *maxJobs), ttl: *ttl, jobs: map[string]*job{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.createJob)
+	mux.HandleFunc("GET /jobs", s.listJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.getJob)
+	mux.HandleFunc("GET /jobs/{id}/results", s.jobResults)
+	mux.HandleFunc("DELETE /jobs/{id}", s.cancelJob)
+	mux.HandleFunc("GET /metrics", s.metrics)
+	srv := &http.Server{Addr: *listen, Handler: s.auth(mux), ReadHeaderTimeout: 10 * time.Second}
+	if *metricsListen != "" {
+		mmux := http.NewServeMux()
+		mmux.HandleFunc("GET /metrics", s.metrics)
+		msrv := &http.Server{Addr: *metricsListen, Handler: mmux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := msrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				logErr("[!] serve: metrics: %v", err)
+				exit(exitFatal)
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		s.cancelAll()
+		sctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(sctx)
+	}()
+	fmt.Fprintf(infoOut, "[*] %s listening on %s, results in %s\n", versionString(), *listen, *dir)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		logErr("[!] serve: %v", err)
+		exit(exitFatal)
+	}
+	exit(exitOK)
+}
+
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				writeJSONError(w, http.StatusUnauthorized, "missing or invalid token")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// jobConfig turns a request into the Config the job runs on, parsing the
+// options with the command-line flag definitions so that defaults and
+// validation match the CLI.
+func (s *server) jobConfig(req jobRequest) (*Config, error) {
+	flagName, ok := jobModes[req.Mode]
+	if !ok {
+		return nil, fmt.Errorf("unknown mode %q", req.Mode)
+	}
+	c := &Config{}
+	fs := flag.NewFlagSet("job", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerFlags(fs, c)
+	if req.Mode == "subdomains" {
+		fs.Set(flagName, "true")
+	} else {
+		if strings.TrimSpace(req.Value) == "" {
+			return nil, fmt.Errorf("mode %s needs a value", req.Mode)
+		}
+		if fileExists(req.Value) {
+			// the CLI would read it as a list file
+			return nil, errors.New("value must be a list, not a file name")
+		}
+		fs.Set(flagName, req.Value)
+	}
+	names := make([]string, 0, len(req.Options))
+	for k := range req.Options {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if !jobOptions[k] {
+			return nil, fmt.Errorf("option %q is not allowed in jobs", k)
+		}
+		if err := fs.Set(k, fmt.Sprint(req.Options[k])); err != nil {
+			return nil, fmt.Errorf("option %s: %v", k, err)
+		}
+	}
+	for _, v := range []string{c.exclusions, c.filterExtensions} {
+		if v != "" && fileExists(v) {
+			return nil, errors.New("options must be lists, not file names")
+		}
+	}
+	var err error
+	if c.sources, err = parseSources(c.sourceList); err != nil {
+		return nil, err
+	}
+	if err := c.prepare(); err != nil {
+		return nil, err
+	}
+	c.engine, c.engines = s.base.engine, s.base.engines
+	c.client, c.provider, c.providers = s.base.client, s.base.provider, s.base.providers
+	c.stats, c.pace, c.verbose = s.base.stats, s.base.pace, s.base.verbose
+	c.jsonOutput = true
+	c.silent = true
+	return c, nil
+}
+
+func (s *server) createJob(w http.ResponseWriter, r *http.Request) {
+	var req jobRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if req.Target != "" {
+		req.Targets = append(req.Targets, req.Target)
+	}
+	var targets []string
+	for _, t := range req.Targets {
+		if strings.TrimSpace(t) == "" {
+			continue
+		}
+		n, err := banshee.NormalizeTarget(t)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		targets = append(targets, n)
+	}
+	if len(targets) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "no targets")
+		return
+	}
+	cfg, err := s.jobConfig(req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id := newRunID()
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		ID:       id,
+		Targets:  targets,
+		Mode:     req.Mode,
+		State:    "queued",
+		Created:  time.Now(),
+		cfg:      cfg,
+		path:     filepath.Join(s.dir, id+".jsonl"),
+		cancel:   cancel,
+		finished: make(chan struct{}),
+	}
+	cfg.outputPath = j.path
+	cfg.written = &j.written
+	s.mu.Lock()
+	s.prune(time.Now())
+	s.jobs[id] = j
+	s.mu.Unlock()
+	go s.run(ctx, j)
+	writeJSON(w, http.StatusAccepted, j.snapshot())
+}
+
+// run waits for a free slot, then searches every target of j in turn,
+// deduplicating results across them like a -f run.
+func (s *server) run(ctx context.Context, j *job) {
+	defer close(j.finished)
+	defer j.cancel()
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		j.setState("cancelled", "")
+		return
+	}
+	j.setState("running", "")
+	lg.event("job_start", "job", j.ID, "mode", j.Mode, "targets", len(j.Targets))
+	j.cfg.seen = NewSafeSet(j.cfg.output().compact)
+	for _, t := range j.Targets {
+		if ctx.Err() != nil {
+			break
+		}
+		c2 := *j.cfg
+		c2.target = t
+		c2.track = &targetTracker{}
+		j.mu.Lock()
+		j.Current = t
+		j.mu.Unlock()
+		c2.runTarget(ctx)
+		j.mu.Lock()
+		j.Done++
+		j.Current = ""
+		j.mu.Unlock()
+	}
+	closeOutput(j.path)
+	switch {
+	case ctx.Err() != nil:
+		j.setState("cancelled", "")
+	case keysRanOut.Load() && s.base.quotaState().Usable == 0:
+		j.setState("failed", "every API key is exhausted")
+	default:
+		j.setState("done", "")
+	}
+	lg.event("job_done", "job", j.ID, "state", j.State, "results", j.written.Load())
+}
+
+func (j *job) setState(state, msg string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.State, j.Error = state, msg
+	switch state {
+	case "running":
+		j.Started = time.Now()
+	case "done", "cancelled", "failed":
+		j.Finished = time.Now()
+	}
+}
+
+// snapshot returns a copy of j safe to encode while it keeps running.
+func (j *job) snapshot() *job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return &job{
+		ID: j.ID, Targets: j.Targets, Mode: j.Mode, State: j.State, Error: j.Error,
+		Done: j.Done, Current: j.Current, Results: int(j.written.Load()),
+		Created: j.Created, Started: j.Started, Finished: j.Finished,
+	}
+}
+
+// prune forgets the jobs that finished more than s.ttl before now. It is
+// called with s.mu held.
+func (s *server) prune(now time.Time) {
+	for id, j := range s.jobs {
+		j.mu.Lock()
+		expired := !j.Finished.IsZero() && now.Sub(j.Finished) > s.ttl
+		j.mu.Unlock()
+		if expired {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+func (s *server) lookup(w http.ResponseWriter, r *http.Request) *job {
+	s.mu.Lock()
+	s.prune(time.Now())
+	j := s.jobs[r.PathValue("id")]
+	s.mu.Unlock()
+	if j == nil {
+		writeJSONError(w, http.StatusNotFound, "no such job")
+	}
+	return j
+}
+
+func (s *server) listJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.prune(time.Now())
+	all := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		all = append(all, j)
+	}
+	s.mu.Unlock()
+	out := make([]*job, len(all))
+	for i, j := range all {
+		out[i] = j.snapshot()
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Created.Before(out[b].Created) })
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *server) getJob(w http.ResponseWriter, r *http.Request) {
+	if j := s.lookup(w, r); j != nil {
+		writeJSON(w, http.StatusOK, j.snapshot())
+	}
+}
+
+// cancelJob stops the job and forgets it, answering with its final state.
+func (s *server) cancelJob(w http.ResponseWriter, r *http.Request) {
+	if j := s.lookup(w, r); j != nil {
+		j.cancel()
+		<-j.finished
+		s.mu.Lock()
+		delete(s.jobs, j.ID)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, j.snapshot())
+	}
+}
+
+func (s *server) cancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		j.cancel()
+	}
+}
+
+// jobResults streams the job's results file as JSON lines, following it
+// until the job ends or the client goes away. Subdomain jobs write bare
+// hosts, which are wrapped as {"host": ...}.
+func (s *server) jobResults(w http.ResponseWriter, r *http.Request) {
+	j := s.lookup(w, r)
+	if j == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	fl, _ := w.(http.Flusher)
+	var off int64
+	var partial []byte
+	tick := time.NewTicker(500 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		var ended bool
+		select {
+		case <-j.finished:
+			ended = true
+		default:
+		}
+		flushOutput(j.path)
+		if f, err := os.Open(j.path); err == nil {
+			f.Seek(off, io.SeekStart)
+			br := bufio.NewReader(f)
+			for {
+				b, err := br.ReadBytes('\n')
+				off += int64(len(b))
+				if err != nil {
+					partial = append(partial, b...)
+					break
+				}
+				line := strings.TrimSpace(string(append(partial, b...)))
+				partial = partial[:0]
+				if line == "" {
+					continue
+				}
+				if !strings.HasPrefix(line, "{") {
+					hb, _ := json.Marshal(map[string]string{"host": line})
+					line = string(hb)
+				}
+				io.WriteString(w, line+"\n")
+			}
+			f.Close()
+		}
+		if fl != nil {
+			fl.Flush()
+		}
+		if ended {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-j.finished:
+		case <-tick.C:
+		}
+	}
+}
+
+// pacer spaces out search requests across every job of a serve process.
+type pacer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next request may be sent, or until ctx is done,
+// returning its error. A nil pacer never waits.
+func (p *pacer) wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next
+	if at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(p.interval)
+	p.mu.Unlock()
+	t := time.NewTimer(time.Until(at))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
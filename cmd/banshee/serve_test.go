@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// testServer returns a serve server whose jobs search p, pacing requests
+// interval apart.
+func testServer(t *testing.T, p banshee.Provider, interval time.Duration) *server {
+	t.Helper()
+	base := &Config{stats: &engineStats{}}
+	fs := flag.NewFlagSet("defaults", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerFlags(fs, base)
+	base.provider = p
+	base.engine = p.Name()
+	base.engines = []string{base.engine}
+	base.providers = map[string]banshee.Provider{base.engine: p}
+	if interval > 0 {
+		base.pace = &pacer{interval: interval}
+	}
+	t.Cleanup(func() {
+		found.Store(0)
+		saved.Store(0)
+	})
+	return &server{base: base, dir: t.TempDir(), slots: make(chan struct{}, 4), ttl: time.Hour, jobs: map[string]*job{}}
+}
+
+// submit posts a job and returns it.
+func submit(t *testing.T, s *server, body string) *job {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.createJob(w, httptest.NewRequest("POST", "/jobs", strings.NewReader(body)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("POST /jobs = %d %s", w.Code, w.Body)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if strings.Contains(w.Body.String(), j.ID) {
+			return j
+		}
+	}
+	t.Fatal("job not registered")
+	return nil
+}
+
+// Jobs running side by side each count what they write to their own file.
+// Run with -race.
+func TestServeJobs(t *testing.T) {
+	s := testServer(t, newFakeProvider(nil), 0)
+	var wg sync.WaitGroup
+	jobs := make([]*job, 4)
+	for i := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v := []string{"false", "true"}[i%2]
+			jobs[i] = submit(t, s, `{"target": "example.com", "mode": "dork", "value": "inurl:admin", "options": {"pages": 2, "raw-urls": `+v+`}}`)
+		}()
+	}
+	wg.Wait()
+	for _, j := range jobs {
+		<-j.finished
+		b, err := os.ReadFile(j.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := bytes.Count(b, []byte("\n"))
+		if snap := j.snapshot(); snap.State != "done" || snap.Results != lines || lines != 4 {
+			t.Errorf("job %s: %s with %d results, file has %d lines; want done with 4", j.ID, snap.State, snap.Results, lines)
+		}
+	}
+}
+
+// Cancelling a job doesn't wait out the request pacing, and forgets the
+// job.
+func TestServeCancelWhilePacing(t *testing.T) {
+	s := testServer(t, newFakeProvider(nil), time.Hour)
+	j := submit(t, s, `{"target": "example.com", "mode": "dork", "value": "inurl:admin", "options": {"pages": 3}}`)
+	for len(j.cfg.provider.(*fakeProvider).sent()) == 0 {
+		time.Sleep(time.Millisecond) // the first request goes out at once
+	}
+	start := time.Now()
+	r := httptest.NewRequest("DELETE", "/jobs/"+j.ID, nil)
+	r.SetPathValue("id", j.ID)
+	w := httptest.NewRecorder()
+	s.cancelJob(w, r)
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("DELETE took %v", d)
+	}
+	if !strings.Contains(w.Body.String(), `"state":"cancelled"`) {
+		t.Errorf("DELETE answered %s, want the cancelled job", w.Body)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[j.ID]; ok {
+		t.Error("cancelled job still listed")
+	}
+}
+
+// Finished jobs are forgotten once -job-ttl has passed; running ones never.
+func TestServePrune(t *testing.T) {
+	s := testServer(t, newFakeProvider(nil), 0)
+	now := time.Now()
+	s.jobs["old"] = &job{ID: "old", State: "done", Finished: now.Add(-2 * time.Hour)}
+	s.jobs["recent"] = &job{ID: "recent", State: "done", Finished: now.Add(-time.Minute)}
+	s.jobs["running"] = &job{ID: "running", State: "running", Started: now.Add(-2 * time.Hour)}
+	w := httptest.NewRecorder()
+	s.listJobs(w, httptest.NewRequest("GET", "/jobs", nil))
+	for id, want := range map[string]bool{"old": false, "recent": true, "running": true} {
+		if _, ok := s.jobs[id]; ok != want {
+			t.Errorf("job %s kept = %v, want %v", id, ok, want)
+		}
+	}
+}
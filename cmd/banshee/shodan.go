@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// Shodan enrichment (-shodan) for IP and CIDR targets: each address is
+// looked up with the Shodan host API, its open ports are reported and the
+// hostnames Shodan knows for it are searched like -f targets. The key comes
+// from $SHODAN_API_KEY or shodan-keys.txt and is separate from the search
+// key pools.
+
+const (
+	shodanHostURL = "https://api.shodan.io/shodan/host/"
+	// largest CIDR expanded, to keep a typo like /8 from burning credits
+	shodanMaxAddrs = 1024
+)
+
+type shodanHost struct {
+	Hostnames []string `json:"hostnames"`
+	Ports     []int    `json:"ports"`
+	Error     string   `json:"error"`
+}
+
+// isIPTarget reports whether target is an IP address or a CIDR range.
+func isIPTarget(target string) bool {
+	if net.ParseIP(target) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(target)
+	return err == nil
+}
+
+// expandTarget lists the addresses of an IP or CIDR target.
+func expandTarget(target string) ([]string, error) {
+	if ip := net.ParseIP(target); ip != nil {
+		return []string{ip.String()}, nil
+	}
+	pfx, err := netip.ParsePrefix(target)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for a := pfx.Masked().Addr(); pfx.Contains(a); a = a.Next() {
+		if len(out) == shodanMaxAddrs {
+			return nil, fmt.Errorf("%s has more than %d addresses", target, shodanMaxAddrs)
+		}
+		out = append(out, a.String())
+	}
+	return out, nil
+}
+
+func shodanKey() (string, error) {
+	if k := strings.TrimSpace(os.Getenv("SHODAN_API_KEY")); k != "" {
+		return k, nil
+	}
+	keys, err := loadAPIKeysDefault("shodan")
+	if err != nil {
+		return "", fmt.Errorf("no Shodan key in $SHODAN_API_KEY or shodan-keys.txt: %w", err)
+	}
+	return keys[0], nil
+}
+
+// shodanAttack looks up every address of c.target, then runs the selected
+// mode against each address and each hostname found.
+func (c *Config) shodanAttack(ctx context.Context) {
+	key, err := shodanKey()
+	if err != nil {
+		logErr("[!] %v", err)
+		exit(exitFatal)
+	}
+	lg.addSecrets(key)
+	addrs, err := expandTarget(c.target)
+	if err != nil {
+		logErr("[!] %v", err)
+		exit(exitFatal)
+	}
+	// Shodan allows one request per second
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	ports := map[string][]int{}
+	var targets []string
+	seen := map[string]bool{}
+	for i, a := range addrs {
+		if i > 0 {
+			select {
+			case <-tick.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+		h, err := c.shodanLookup(ctx, key, a)
+		if err != nil {
+			logv(c.verbose, "Shodan: %s: %v", a, err)
+			continue
+		}
+		sort.Ints(h.Ports)
+		logInfo("[shodan] %s ports=%s hostnames=%s", a, joinInts(h.Ports), strings.Join(h.Hostnames, ","))
+		for _, t := range append([]string{a}, h.Hostnames...) {
+			t = banshee.ASCIIHost(strings.ToLower(t))
+			if !seen[t] {
+				seen[t] = true
+				targets = append(targets, t)
+			}
+			ports[t] = h.Ports
+		}
+	}
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			return
+		}
+		c2 := *c
+		c2.target = t
+		c2.track = &targetTracker{}
+		c2.shodanPorts = ports[t]
+		c2.runTarget(ctx)
+	}
+}
+
+func (c *Config) shodanLookup(ctx context.Context, key, addr string) (*shodanHost, error) {
+	rctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	body, status, err := banshee.HTTPGet(rctx, c.client, shodanHostURL+addr+"?key="+key)
+	if err != nil {
+		return nil, err
+	}
+	var h shodanHost
+	if err := json.Unmarshal(body, &h); err != nil {
+		return nil, fmt.Errorf("decode error: %w", err)
+	}
+	if h.Error != "" {
+		return nil, errors.New(h.Error)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("status %d", status)
+	}
+	return &h, nil
+}
+
+func joinInts(v []int) string {
+	s := make([]string, len(v))
+	for i, n := range v {
+		s[i] = fmt.Sprint(n)
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// --- Slack notifications ---
+
+// slackTextLimit keeps messages well below Slack's 40,000 character cap
+// and readable in a channel.
+const slackTextLimit = 3000
+
+// notifySlack posts s to the -slack-webhook incoming webhook, with the
+// sample as a code block.
+func (c *Config) notifySlack(s runSummary) error {
+	text := fmt.Sprintf("*%s*", s.headline())
+	if len(s.Sample) > 0 {
+		text += "\n```" + truncateSample(s.Sample, slackTextLimit-len(text)-8) + "```"
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	return c.postWithRetry(c.slackWebhook, body, map[string]string{"Content-Type": "application/json"})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// --- Console colors ---
+
+// tone is how a console message is highlighted.
+type tone int
+
+const (
+	tonePlain  tone = iota
+	toneError       // red: "[!]" messages
+	toneKey         // yellow: API keys running out or coming back
+	toneHeader      // cyan: per-target headers and progress
+)
+
+var toneCodes = map[tone]string{
+	toneError:  "\033[31m",
+	toneKey:    "\033[33m",
+	toneHeader: "\033[36m",
+}
+
+// colorStderr is set when stderr diagnostics may be colored: stderr is a
+// terminal, and neither -no-color nor NO_COLOR (https://no-color.org) is
+// set. stdout is never colored, so results stay clean in pipes.
+var colorStderr bool
+
+func initColor(noColor bool) {
+	colorStderr = !noColor && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && isTerminal(os.Stderr)
+}
+
+// toneOf classifies a console message by the prefixes banshee's messages
+// already use.
+func toneOf(msg string) tone {
+	switch {
+	case strings.HasPrefix(msg, "API key "), strings.HasPrefix(msg, "No valid API keys"):
+		return toneKey
+	case strings.HasPrefix(msg, "[!]"):
+		return toneError
+	case strings.HasPrefix(msg, "Target: "), strings.Contains(msg, "] processing "):
+		return toneHeader
+	}
+	return tonePlain
+}
+
+// paint wraps msg in the color of its tone when it is bound for stderr and
+// colors are on.
+func paint(toStderr bool, msg string) string {
+	if !colorStderr || !toStderr {
+		return msg
+	}
+	code, ok := toneCodes[toneOf(msg)]
+	if !ok {
+		return msg
+	}
+	return code + msg + "\033[0m"
+}
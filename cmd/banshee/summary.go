@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// --- Per-target run summary (-summary) ---
+
+// targetSummary writes one CSV row per -f target as it completes. Each
+// row is flushed at once, so a crashed run still leaves the rows of the
+// targets it finished.
+type targetSummary struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *csv.Writer
+}
+
+// summaryRow is the outcome of one target. Status is ok, timeout (cut
+// short by -domain-timeout), errors (given up under -max-errors), no-keys,
+// interrupted or invalid (not a usable target, so never searched).
+type summaryRow struct {
+	Target   string
+	Status   string
+	Results  int64
+	Requests int
+	Pages    int
+	Errors   int
+	Duration time.Duration
+
+	start time.Time
+}
+
+var summaryHeader = []string{"target", "status", "results", "requests", "pages", "errors", "duration_s"}
+
+var summaryRun *targetSummary
+
+// openSummary opens path for appending, writing the header when the file
+// is new or empty, so a -resume'd run adds to the same summary.
+func openSummary(path string) (*targetSummary, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &targetSummary{f: f, w: csv.NewWriter(f)}
+	if fi, err := f.Stat(); err == nil && fi.Size() == 0 {
+		s.w.Write(summaryHeader)
+		s.w.Flush()
+	}
+	return s, nil
+}
+
+// row appends r. A nil summary records nothing.
+func (s *targetSummary) row(r summaryRow) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return
+	}
+	s.w.Write([]string{
+		r.Target, r.Status,
+		strconv.FormatInt(r.Results, 10), strconv.Itoa(r.Requests), strconv.Itoa(r.Pages), strconv.Itoa(r.Errors),
+		strconv.FormatFloat(r.Duration.Seconds(), 'f', 1, 64),
+	})
+	s.w.Flush()
+	if err := s.w.Error(); err != nil {
+		logErr("[!] cannot write summary file: %v", err)
+		s.f.Close()
+		s.f = nil
+	}
+}
+
+func (s *targetSummary) close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return
+	}
+	s.f.Close()
+	s.f = nil
+}
+
+// startSummary begins the summary row of c.target, holding the counters
+// it is measured from until endSummary.
+func (c *Config) startSummary() summaryRow {
+	return summaryRow{Target: c.target, Results: found.Load(), Requests: c.quotaState().Requests, start: time.Now()}
+}
+
+// endSummary fills r with what c.target's run found and used. Results
+// under -count are the target's count, as nothing is written before it.
+func (c *Config) endSummary(r *summaryRow) {
+	r.Results = found.Load() - r.Results
+	if c.count != nil {
+		r.Results = int64(c.count.len())
+	}
+	r.Requests = c.quotaState().Requests - r.Requests
+	r.Duration = time.Since(r.start)
+	if t := c.track; t != nil {
+		t.mu.Lock()
+		r.Pages, r.Errors = t.pages, t.failed
+		t.mu.Unlock()
+	}
+}
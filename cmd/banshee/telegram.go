@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// --- Telegram notifications ---
+
+// telegramLimit is the most characters Telegram accepts in one message.
+const telegramLimit = 4096
+
+const telegramAPIURL = "https://api.telegram.org/bot"
+
+// notifyTelegram sends s as plain text through the Bot API, split over
+// several messages when it does not fit in one.
+func (c *Config) notifyTelegram(s runSummary) error {
+	lines := append([]string{s.headline()}, s.Sample...)
+	if more := s.New - len(s.Sample); more > 0 && len(s.Sample) > 0 {
+		lines = append(lines, "... and "+commas(int64(more))+" more")
+	}
+	u := telegramAPIURL + c.telegramToken + "/sendMessage"
+	for _, msg := range splitMessage(lines, telegramLimit) {
+		body, err := json.Marshal(map[string]any{
+			"chat_id":                  c.telegramChat,
+			"text":                     msg,
+			"disable_web_page_preview": true,
+		})
+		if err != nil {
+			return err
+		}
+		if err := c.postWithRetry(u, body, map[string]string{"Content-Type": "application/json"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitMessage packs lines into messages of at most limit characters,
+// breaking only between lines unless a single line is too long.
+func splitMessage(lines []string, limit int) []string {
+	var out []string
+	var b strings.Builder
+	n := 0 // characters in b; Telegram counts characters, not bytes
+	for _, l := range lines {
+		r := []rune(l)
+		for len(r) > limit {
+			if n > 0 {
+				out = append(out, b.String())
+				b.Reset()
+				n = 0
+			}
+			out = append(out, string(r[:limit]))
+			r = r[limit:]
+		}
+		if n > 0 && n+1+len(r) > limit {
+			out = append(out, b.String())
+			b.Reset()
+			n = 0
+		}
+		if n > 0 {
+			b.WriteByte('\n')
+			n++
+		}
+		b.WriteString(string(r))
+		n += len(r)
+	}
+	if n > 0 {
+		out = append(out, b.String())
+	}
+	return out
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- update: self-update from GitHub releases ---
+
+const releasesURL = "https://api.github.com/repos/Vulnpire/Banshee/releases/latest"
+
+// exitUpdateAvailable is returned by "update -check-only" when a newer
+// release exists, so cron jobs can act on it.
+const exitUpdateAvailable = 10
+
+type release struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// updateMain implements "banshee update".
+func updateMain(args []string) {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	checkOnly := fs.Bool("check-only", false, "Only report whether a newer release exists")
+	proxy := fs.String("proxy", "", "Specify an [protocol://]host[:port] proxy")
+	fs.Parse(args)
+
+	cl, err := buildHTTPClient(*proxy, proxyFlag)
+	if err != nil {
+		logErr("[!] Invalid proxy: %v", err)
+		os.Exit(exitFatal)
+	}
+	cl.Timeout = 5 * time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	rel, err := latestRelease(ctx, cl)
+	if err != nil {
+		logErr("[!] update: cannot check releases: %v", err)
+		os.Exit(exitFatal)
+	}
+	latest := strings.TrimPrefix(rel.TagName, "v")
+	if compareVersions(latest, version) <= 0 {
+		fmt.Fprintf(infoOut, "[*] banshee %s is up to date\n", version)
+		return
+	}
+	fmt.Fprintf(infoOut, "[*] banshee %s is available (installed: %s): %s\n", latest, version, rel.HTMLURL)
+	if *checkOnly {
+		os.Exit(exitUpdateAvailable)
+	}
+	if err := installRelease(ctx, cl, rel); err != nil {
+		logErr("[!] update: %v", err)
+		os.Exit(exitFatal)
+	}
+}
+
+func latestRelease(ctx context.Context, cl *http.Client) (*release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releasesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if t := os.Getenv("GITHUB_TOKEN"); t != "" {
+		// avoids the low unauthenticated rate limit
+		req.Header.Set("Authorization", "Bearer "+t)
+	}
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API status %d", resp.StatusCode)
+	}
+	var rel release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, err
+	}
+	if rel.TagName == "" {
+		return nil, errors.New("release has no tag")
+	}
+	return &rel, nil
+}
+
+// compareVersions compares dotted numeric versions ("1.33.7"), returning
+// -1, 0 or 1. Missing or non-numeric parts count as 0.
+func compareVersions(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x, _ = strconv.Atoi(strings.TrimFunc(pa[i], func(r rune) bool { return r < '0' || r > '9' }))
+		}
+		if i < len(pb) {
+			y, _ = strconv.Atoi(strings.TrimFunc(pb[i], func(r rune) bool { return r < '0' || r > '9' }))
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// installRelease downloads the asset for this platform, checks it against
+// the release's checksums file and replaces the running executable.
+func installRelease(ctx context.Context, cl *http.Client, rel *release) error {
+	var assetName, assetURL, sumsURL string
+	for _, a := range rel.Assets {
+		n := strings.ToLower(a.Name)
+		switch {
+		case strings.Contains(n, "checksums"):
+			sumsURL = a.URL
+		case assetURL == "" && strings.Contains(n, runtime.GOOS) && strings.Contains(n, runtime.GOARCH):
+			assetName, assetURL = a.Name, a.URL
+		}
+	}
+	if assetURL == "" {
+		return fmt.Errorf("no release asset for %s/%s; see %s", runtime.GOOS, runtime.GOARCH, rel.HTMLURL)
+	}
+	if sumsURL == "" {
+		return errors.New("release has no checksums file, refusing to install")
+	}
+	sums, err := download(ctx, cl, sumsURL)
+	if err != nil {
+		return fmt.Errorf("checksums: %w", err)
+	}
+	want := checksumFor(sums, assetName)
+	if want == "" {
+		return fmt.Errorf("%s is not listed in the checksums file", assetName)
+	}
+	data, err := download(ctx, cl, assetURL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", assetName, err)
+	}
+	got := sha256.Sum256(data)
+	if hex.EncodeToString(got[:]) != want {
+		return fmt.Errorf("%s: checksum mismatch", assetName)
+	}
+	bin, err := extractBinary(assetName, data)
+	if err != nil {
+		return err
+	}
+	return replaceExecutable(bin, rel.TagName)
+}
+
+func download(ctx context.Context, cl *http.Client, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 200<<20))
+}
+
+// checksumFor finds name in a "<sha256>  <file>" checksums listing.
+func checksumFor(sums []byte, name string) string {
+	sc := bufio.NewScanner(bytes.NewReader(sums))
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) == 2 && strings.TrimPrefix(f[1], "*") == name {
+			return strings.ToLower(f[0])
+		}
+	}
+	return ""
+}
+
+// extractBinary returns the banshee executable from a .tar.gz or .zip
+// asset, or the asset itself when it is a bare binary.
+func extractBinary(name string, data []byte) ([]byte, error) {
+	isBin := func(n string) bool {
+		b := strings.ToLower(filepath.Base(n))
+		return b == "banshee" || b == "banshee.exe"
+	}
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(gz)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if h.Typeflag == tar.TypeReg && isBin(h.Name) {
+				return io.ReadAll(tr)
+			}
+		}
+	case strings.HasSuffix(name, ".zip"):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if isBin(f.Name) {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+		}
+	default:
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s does not contain a banshee executable", name)
+}
+
+// replaceExecutable swaps the running binary for bin through a rename in
+// the same directory. When that directory is not writable, the new binary
+// is saved in the current directory with instructions instead.
+func replaceExecutable(bin []byte, tag string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".banshee-update-*")
+	if err != nil {
+		alt := "banshee-" + tag
+		if runtime.GOOS == "windows" {
+			alt += ".exe"
+		}
+		if werr := os.WriteFile(alt, bin, 0o755); werr != nil {
+			return fmt.Errorf("cannot write next to %s (%v) or to %s: %v", exe, err, alt, werr)
+		}
+		fmt.Fprintf(infoOut, "[*] %s is not writable; new version saved as %s, move it over %s (e.g. with sudo)\n", filepath.Dir(exe), alt, exe)
+		return nil
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bin); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+	old := ""
+	if runtime.GOOS == "windows" {
+		// a running executable can be renamed but not replaced
+		old = exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		if old != "" {
+			os.Rename(old, exe)
+		}
+		return err
+	}
+	fmt.Fprintf(infoOut, "[*] updated %s to %s\n", exe, tag)
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Vulnpire/banshee/pkg/banshee"
+)
+
+// VirusTotal (-sources vt) adds the subdomains VirusTotal has seen to
+// subdomain mode. The key comes from -vt-key, $VT_API_KEY or vt-keys.txt.
+
+const vtSubdomainsURL = "https://www.virustotal.com/api/v3/domains/%s/relationships/subdomains"
+
+type vtPage struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+	Meta struct {
+		Cursor string `json:"cursor"`
+	} `json:"meta"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// errVTRateLimited marks a 429; the public API allows 4 requests a minute.
+var errVTRateLimited = errors.New("rate limited")
+
+func (c *Config) vtKey() (string, error) {
+	if c.vtAPIKey != "" {
+		return c.vtAPIKey, nil
+	}
+	if k := strings.TrimSpace(os.Getenv("VT_API_KEY")); k != "" {
+		return k, nil
+	}
+	keys, err := loadAPIKeysDefault("vt")
+	if err != nil {
+		return "", fmt.Errorf("no VirusTotal key in -vt-key, $VT_API_KEY or vt-keys.txt: %w", err)
+	}
+	return keys[0], nil
+}
+
+// vtHosts pages through the subdomains relationship of domain. On a 429 it
+// waits and retries the same page, doubling the wait up to a few minutes.
+func (c *Config) vtHosts(ctx context.Context, domain string) ([]string, error) {
+	key, err := c.vtKey()
+	if err != nil {
+		return nil, err
+	}
+	lg.addSecrets(key)
+	var hosts []string
+	cursor := ""
+	backoff := 15 * time.Second
+	for {
+		page, err := c.vtPage(ctx, key, domain, cursor)
+		if errors.Is(err, errVTRateLimited) && backoff <= 4*time.Minute {
+			logv(c.verbose, "VirusTotal rate limit, waiting %s", backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return hosts, ctx.Err()
+			}
+			backoff *= 2
+			continue
+		}
+		if err != nil {
+			return hosts, err
+		}
+		for _, d := range page.Data {
+			hosts = append(hosts, banshee.ASCIIHost(strings.ToLower(d.ID)))
+		}
+		if page.Meta.Cursor == "" || len(page.Data) == 0 {
+			return hosts, nil
+		}
+		cursor = page.Meta.Cursor
+	}
+}
+
+func (c *Config) vtPage(ctx context.Context, key, domain, cursor string) (*vtPage, error) {
+	u := fmt.Sprintf(vtSubdomainsURL, url.PathEscape(domain)) + "?limit=40"
+	if cursor != "" {
+		u += "&cursor=" + url.QueryEscape(cursor)
+	}
+	rctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(rctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-apikey", key)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, errVTRateLimited
+	}
+	var p vtPage
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil, fmt.Errorf("decode error: %w", err)
+	}
+	if p.Error != nil {
+		if p.Error.Code == "QuotaExceededError" {
+			return nil, errVTRateLimited
+		}
+		return nil, fmt.Errorf("%s: %s", p.Error.Code, p.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return &p, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Wayback Machine CDX (-sources wayback) adds archived URLs of the target
+// to extension, dictionary and subdomain results. It needs no API key and
+// costs no search quota.
+
+const (
+	waybackCDXURL   = "https://web.archive.org/cdx/search/cdx"
+	waybackPageSize = 5000
+)
+
+// waybackURLs returns up to -wayback-limit archived URLs for c.target (and
+// its subdomains with -a), one per URL key, following CDX resume keys.
+func (c *Config) waybackURLs(ctx context.Context) ([]string, error) {
+	pattern := c.target + "/*"
+	if c.includeSubdomains {
+		pattern = "*." + pattern
+	}
+	// CDX responses for big domains are large and slow; rely on ctx
+	// instead of the API timeout
+	cl := *c.client
+	cl.Timeout = 0
+	var out []string
+	resume := ""
+	for {
+		limit := waybackPageSize
+		if c.waybackLimit > 0 && c.waybackLimit-len(out) < limit {
+			limit = c.waybackLimit - len(out)
+		}
+		if limit <= 0 {
+			return out, nil
+		}
+		v := url.Values{}
+		v.Set("url", pattern)
+		v.Set("output", "json")
+		v.Set("collapse", "urlkey")
+		v.Set("fl", "original")
+		v.Set("limit", fmt.Sprint(limit))
+		v.Set("showResumeKey", "true")
+		if resume != "" {
+			v.Set("resumeKey", resume)
+		}
+		page, next, err := fetchCDXPage(ctx, &cl, waybackCDXURL+"?"+v.Encode())
+		out = append(out, page...)
+		if err != nil {
+			return out, err
+		}
+		if next == "" {
+			return out, nil
+		}
+		resume = next
+	}
+}
+
+// fetchCDXPage decodes one CDX JSON page row by row: a header row, one row
+// per capture, then, when more pages exist, an empty row and the resume key.
+func fetchCDXPage(ctx context.Context, cl *http.Client, u string) (urls []string, resume string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("wayback: status %d", resp.StatusCode)
+	}
+	dec := json.NewDecoder(resp.Body)
+	if _, err := dec.Token(); err != nil {
+		// an empty body means no captures
+		return nil, "", nil
+	}
+	header, afterEmpty := true, false
+	for dec.More() {
+		var row []string
+		if err := dec.Decode(&row); err != nil {
+			return urls, "", fmt.Errorf("wayback: %w", err)
+		}
+		switch {
+		case header:
+			header = false
+		case len(row) == 0:
+			afterEmpty = true
+		case afterEmpty:
+			resume = row[0]
+		default:
+			urls = append(urls, row[0])
+		}
+	}
+	return urls, resume, nil
+}
+
+// waybackLinks fetches and scopes the archived URLs for the current target.
+// Failures are reported and whatever was fetched is used.
+func (c *Config) waybackLinks(ctx context.Context, extMode bool) []string {
+	if !c.sources["wayback"] || ctx.Err() != nil {
+		return nil
+	}
+	links, err := c.waybackURLs(ctx)
+	if err != nil {
+		logErr("[!] wayback: %v (using %d URLs fetched so far)", err, len(links))
+	}
+	return c.scopeLinks(links, extMode)
+}
+
+// waybackResults turns the links match accepts into results tagged with the
+// term match returns.
+func waybackResults(links []string, match func(string) (string, bool)) []result {
+	var out []result
+	for _, l := range links {
+		if term, ok := match(l); ok {
+			out = append(out, result{url: l, term: term, query: "wayback", engine: "wayback"})
+		}
+	}
+	return out
+}
+
+// hasExtension reports whether the path of link ends in .ext.
+func hasExtension(link, ext string) bool {
+	p := link
+	if u, err := url.Parse(link); err == nil {
+		p = u.Path
+	}
+	return strings.HasSuffix(strings.ToLower(p), "."+strings.ToLower(strings.TrimPrefix(ext, ".")))
+}
+
+// matchTerms matches links containing one of terms, like inurl: does.
+func matchTerms(terms []string) func(string) (string, bool) {
+	return func(l string) (string, bool) {
+		ll := strings.ToLower(l)
+		for _, t := range terms {
+			if t = strings.TrimSpace(t); t != "" && strings.Contains(ll, strings.ToLower(t)) {
+				return t, true
+			}
+		}
+		return "", false
+	}
+}
@@ -1,3 +1,7 @@
 module github.com/Vulnpire/banshee
 
 go 1.24.5
+
+require golang.org/x/net v0.47.0
+
+require golang.org/x/text v0.31.0 // indirect
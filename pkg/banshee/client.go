@@ -0,0 +1,124 @@
+package banshee
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ClientOptions configures NewClient.
+type ClientOptions struct {
+	// Engine is one of Engines; "google" when empty.
+	Engine string
+	Keys   []string
+	// HTTPClient sends the API requests; http.DefaultClient when nil.
+	HTTPClient *http.Client
+	// Pages is the most result pages fetched per query; 10 when 0.
+	Pages int
+	// Delay is the pause between two requests.
+	Delay time.Duration
+	// Filter is applied to every page of links. Its Target is set from
+	// the QuerySpec of each search.
+	Filter Filter
+	// OnEvent and CX are passed to the provider, see ProviderOptions.
+	OnEvent func(Event)
+	CX      string
+}
+
+// Client runs searches against one engine. It is safe for concurrent use;
+// searches share the key pool.
+type Client struct {
+	provider Provider
+	opts     ClientOptions
+}
+
+// Result is a result URL with the term and query that found it.
+type Result struct {
+	URL   string
+	Term  string
+	Query string
+}
+
+// NewClient returns a Client for opts.Engine using opts.Keys.
+func NewClient(opts ClientOptions) (*Client, error) {
+	if opts.Engine == "" {
+		opts.Engine = "google"
+	}
+	if len(opts.Keys) == 0 {
+		return nil, errors.New("no API keys")
+	}
+	if opts.Pages <= 0 {
+		opts.Pages = 10
+	}
+	p, err := NewProvider(opts.Engine, opts.Keys, ProviderOptions{HTTPClient: opts.HTTPClient, OnEvent: opts.OnEvent, CX: opts.CX})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{provider: p, opts: opts}, nil
+}
+
+// Provider returns the client's provider, e.g. for its QuotaState.
+func (c *Client) Provider() Provider { return c.provider }
+
+// Search sends the queries of spec page by page, stopping after the first
+// page that brings nothing new, and returns the filtered, deduplicated
+// results. A key that runs out of quota is retired and the request retried
+// with another one. When every key is exhausted or ctx ends, the results
+// found so far are returned with the error.
+func (c *Client) Search(ctx context.Context, spec QuerySpec) ([]Result, error) {
+	f := c.opts.Filter
+	f.Target = spec.Target
+	reqs := BuildQueries(spec)
+	for i := range reqs {
+		reqs[i].Query = c.provider.Rewrite(reqs[i].Query)
+	}
+	seen := map[string]bool{}
+	var out []Result
+	first := true
+	for page := 0; page < c.opts.Pages; page++ {
+		found := false
+		for _, r := range reqs {
+			if !first && c.opts.Delay > 0 {
+				select {
+				case <-time.After(c.opts.Delay):
+				case <-ctx.Done():
+				}
+			}
+			first = false
+			if err := ctx.Err(); err != nil {
+				return out, err
+			}
+			links, err := c.search(ctx, Query{Text: r.Query, Start: page*10 + 1})
+			if err != nil {
+				if errors.Is(err, ErrNoKeys) || ctx.Err() != nil {
+					return out, err
+				}
+				continue
+			}
+			for _, l := range f.Apply(links, spec.Extension != "") {
+				if k := f.Key(l); !seen[k] {
+					seen[k] = true
+					found = true
+					out = append(out, Result{URL: l, Term: r.Term, Query: r.Query})
+				}
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return out, nil
+}
+
+// search sends q, retrying with another key while keys run out of quota
+// or are rejected.
+func (c *Client) search(ctx context.Context, q Query) ([]string, error) {
+	for {
+		links, err := c.provider.Search(ctx, q)
+		if errors.Is(err, ErrKeyExhausted) || errors.Is(err, ErrKeyInvalid) {
+			continue
+		}
+		return links, err
+	}
+}
@@ -0,0 +1,96 @@
+package banshee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Search pages through every query of the spec until a page brings nothing
+// new, moving on to the next key when one runs out, and returns the
+// filtered results once each with the term that found them.
+func TestClientSearch(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := r.URL.Query()
+		keys = append(keys, v.Get("key"))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"code":429,"message":"Quota exceeded.","errors":[{"reason":"dailyLimitExceeded"}]}}`)
+			return
+		}
+		term := strings.TrimSuffix(strings.TrimPrefix(v.Get("q"), `site:example.com inurl:"`), `"`)
+		switch v.Get("start") {
+		case "1":
+			fmt.Fprintf(w, `{"items":[{"link":"https://example.com/%s"},{"link":"https://other.org/%s"}]}`, term, term)
+		case "11":
+			// the shared page was found on page 1 already by admin
+			fmt.Fprintf(w, `{"items":[{"link":"https://example.com/%s/2"},{"link":"https://example.com/admin"}]}`, term)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer srv.Close()
+	to, _ := url.Parse(srv.URL)
+	c, err := NewClient(ClientOptions{
+		Keys:       []string{"k1", "k2"},
+		HTTPClient: &http.Client{Transport: redirect{to}},
+		Pages:      5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Search(context.Background(), QuerySpec{Target: "example.com", Terms: []string{"admin", "login"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Result{
+		{"https://example.com/admin", "admin", `site:example.com inurl:"admin"`},
+		{"https://example.com/login", "login", `site:example.com inurl:"login"`},
+		{"https://example.com/admin/2", "admin", `site:example.com inurl:"admin"`},
+		{"https://example.com/login/2", "login", `site:example.com inurl:"login"`},
+	}
+	if !slices.Equal(res, want) {
+		t.Errorf("Search = %+v, want %+v", res, want)
+	}
+	// the first key once, then the other for two queries on each of three
+	// pages
+	if len(keys) != 7 || slices.Contains(keys[1:], keys[0]) || len(slices.Compact(slices.Clone(keys[1:]))) != 1 {
+		t.Errorf("keys sent = %q, want one key once and the other six times", keys)
+	}
+	if qs := c.Provider().QuotaState(); qs.Usable != 1 {
+		t.Errorf("%d usable keys, want 1", qs.Usable)
+	}
+}
+
+// A search ended by the caller returns what it found before with ctx's
+// error.
+func TestClientSearchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start") != "1" {
+			cancel()
+		}
+		fmt.Fprint(w, pageBody("google", 1, 2))
+	}))
+	defer srv.Close()
+	to, _ := url.Parse(srv.URL)
+	c, err := NewClient(ClientOptions{Keys: []string{"k"}, HTTPClient: &http.Client{Transport: redirect{to}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Search(ctx, QuerySpec{Target: "example.com", Dork: "inurl:admin"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if len(res) != 2 {
+		t.Errorf("Search = %+v, want page 1's two results", res)
+	}
+}
@@ -0,0 +1,18 @@
+// Package banshee holds the reusable parts of the banshee CLI: building
+// Google-dork queries for a target, the search engine providers with their
+// API key pools, and the filtering and normalization applied to result
+// URLs.
+//
+// A Client ties them together for programs that want results without the
+// CLI's output handling:
+//
+//	cl, err := banshee.NewClient(banshee.ClientOptions{
+//		Engine: "google",
+//		Keys:   keys,
+//		Pages:  2,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	res, err := cl.Search(ctx, banshee.QuerySpec{Target: "example.com", Extension: "pdf"})
+package banshee
@@ -0,0 +1,513 @@
+package banshee
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Filter turns the raw links of a search into results for one target. The
+// zero value of every field but Target disables that step.
+type Filter struct {
+	Target string
+	// RawURLs keeps links exactly as returned instead of percent-decoding
+	// them.
+	RawURLs bool
+	// StripParams removes these query parameters, see NewStripSet.
+	StripParams map[string]struct{}
+	// ExcludePaths drops links whose path contains one of these.
+	ExcludePaths []string
+	// ExcludeHosts drops links on one of these hosts or below them.
+	ExcludeHosts []string
+	// ParamsOnly keeps only links with a query string.
+	ParamsOnly bool
+	// DropExtensions drops links whose path ends in one of these
+	// (lowercase, without the dot).
+	DropExtensions map[string]struct{}
+	// UniqueParams dedupes by parameter names only (?id=1 == ?id=2).
+	UniqueParams bool
+	// LooseDedupe ignores the scheme and a leading "www." when deduping.
+	LooseDedupe bool
+	// CaseInsensitive ignores case in the whole URL when deduping, not
+	// only in the scheme and host.
+	CaseInsensitive bool
+}
+
+var googleHostFilter = regexp.MustCompile(`(?i)google`)
+
+// Links keeps links on the target, drops Google-owned ones, decodes them
+// unless RawURLs is set, strips parameters and deduplicates with Key.
+func (f Filter) Links(items []string) []string {
+	out := make([]string, 0, len(items))
+	target := strings.ToLower(f.Target)
+	for _, l := range items {
+		if l == "" {
+			continue
+		}
+		l = ASCIILink(l)
+		if !strings.Contains(strings.ToLower(l), target) {
+			continue
+		}
+		if googleHostFilter.MatchString(l) {
+			continue
+		}
+		if !f.RawURLs {
+			l = DecodeURL(l)
+		}
+		if f.StripParams != nil {
+			l = StripParams(l, f.StripParams)
+		}
+		out = append(out, l)
+	}
+	return UniqueByKey(out, f.Key)
+}
+
+// Apply runs Links and then the path, parameter and extension filters.
+// keepExtensions skips DropExtensions, for searches that asked for a
+// filetype explicitly.
+func (f Filter) Apply(links []string, keepExtensions bool) []string {
+	links = f.Links(links)
+	links = DropPaths(links, f.ExcludePaths)
+	links = DropHosts(links, f.ExcludeHosts)
+	if f.ParamsOnly {
+		links = KeepWithParams(links)
+	}
+	if !keepExtensions {
+		links = DropExtensions(links, f.DropExtensions)
+	}
+	return links
+}
+
+// Key is the comparison key for result URLs under UniqueParams,
+// LooseDedupe and CaseInsensitive. Scheme and host are always compared
+// without regard to case.
+func (f Filter) Key(u string) string {
+	k := u
+	if f.UniqueParams {
+		k = ParamShapeKey(k)
+	}
+	if f.LooseDedupe {
+		k = LooseKey(k)
+	}
+	if f.CaseInsensitive {
+		return strings.ToLower(k)
+	}
+	return lowerHost(k)
+}
+
+// lowerHost lowercases the scheme and host of raw, up to the first "/",
+// "?" or "#" after them.
+func lowerHost(raw string) string {
+	start := 0
+	if i := strings.Index(raw, "://"); i >= 0 {
+		start = i + 3
+	}
+	end := len(raw)
+	if i := strings.IndexAny(raw[start:], "/?#"); i >= 0 {
+		end = start + i
+	}
+	return strings.ToLower(raw[:end]) + raw[end:]
+}
+
+// DecodeURL percent-decodes s. When s is not validly encoded, only the
+// common escapes (%20, %3F, %3D, ...) are replaced.
+func DecodeURL(s string) string {
+	// If standard decoding worked there is nothing left for the
+	// replacement table to do, and running it again would decode sequences
+	// that were literally encoded in the original (%2520 -> %20 -> " ").
+	decoded, err := url.QueryUnescape(s)
+	if err == nil {
+		return decoded
+	}
+	decoded = s
+	repls := map[string]string{
+		"%2520": " ",
+		"%20":   " ",
+		"%3F":   "?",
+		"%3D":   "=",
+		"%21":   "!",
+		"%23":   "#",
+		"%24":   "$",
+		"%2B":   "+",
+		"%26":   "&",
+	}
+	for k, v := range repls {
+		decoded = strings.ReplaceAll(decoded, k, v)
+	}
+	return decoded
+}
+
+// DropExtensions removes links whose URL path ends with one of exts. The
+// parsed path is used so that query strings like "?f=app.js" are kept.
+func DropExtensions(links []string, exts map[string]struct{}) []string {
+	if len(exts) == 0 {
+		return links
+	}
+	out := make([]string, 0, len(links))
+	for _, l := range links {
+		u, err := url.Parse(l)
+		if err == nil {
+			e := strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))
+			if _, drop := exts[e]; drop && e != "" {
+				continue
+			}
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// DropHosts removes links whose host is one of hosts or a subdomain of
+// one. Like DropPaths it backs up a -site: exclusion that Google doesn't
+// always honour.
+func DropHosts(links []string, hosts []string) []string {
+	if len(hosts) == 0 {
+		return links
+	}
+	out := make([]string, 0, len(links))
+	for _, l := range links {
+		h := HostOf(l)
+		excluded := false
+		for _, ex := range hosts {
+			if h == ex || strings.HasSuffix(h, "."+ex) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// DropPaths removes links whose URL path contains one of paths, case
+// insensitively. Google's -inurl: exclusion is unreliable, so this is
+// applied on the client side as well.
+func DropPaths(links []string, paths []string) []string {
+	if len(paths) == 0 {
+		return links
+	}
+	out := make([]string, 0, len(links))
+	for _, l := range links {
+		u, err := url.Parse(l)
+		if err != nil {
+			out = append(out, l)
+			continue
+		}
+		p := strings.ToLower(u.Path)
+		excluded := false
+		for _, ex := range paths {
+			if strings.Contains(p, strings.ToLower(ex)) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// KeepWithParams drops links without a query string.
+func KeepWithParams(links []string) []string {
+	out := make([]string, 0, len(links))
+	for _, l := range links {
+		if u, err := url.Parse(l); err == nil && u.RawQuery != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// ParamShapeKey reduces a URL to scheme://host/path?sorted&param&names so
+// that ?id=1 and ?id=2 compare equal. Unparseable input is returned as is.
+func ParamShapeKey(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	names := make([]string, 0)
+	for name := range u.Query() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return u.Scheme + "://" + strings.ToLower(u.Host) + u.EscapedPath() + "?" + strings.Join(names, "&")
+}
+
+// LooseKey drops the scheme and a leading "www." and lowercases the host.
+func LooseKey(raw string) string {
+	rest := raw
+	if i := strings.Index(rest, "://"); i >= 0 {
+		rest = rest[i+3:]
+	}
+	host, tail := rest, ""
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		host, tail = rest[:i], rest[i:]
+	}
+	return strings.TrimPrefix(strings.ToLower(host), "www.") + tail
+}
+
+// trackingParams is the built-in strip list. Entries ending in "*" are
+// prefixes.
+var trackingParams = []string{
+	"utm_*", "gclid", "gclsrc", "dclid", "gbraid", "wbraid", "fbclid", "msclkid",
+	"yclid", "mc_cid", "mc_eid", "_ga", "_gl", "igshid", "ref_src", "_hsenc",
+	"_hsmi", "mkt_tok", "phpsessid", "jsessionid", "sessionid", "session_id",
+	"aspsessionid", "cfid", "cftoken",
+}
+
+// NewStripSet returns the built-in tracking and session parameters plus
+// extra, for StripParams.
+func NewStripSet(extra []string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, p := range trackingParams {
+		set[p] = struct{}{}
+	}
+	for _, p := range extra {
+		set[strings.ToLower(p)] = struct{}{}
+	}
+	return set
+}
+
+func stripParam(name string, set map[string]struct{}) bool {
+	name = strings.ToLower(name)
+	if _, ok := set[name]; ok {
+		return true
+	}
+	for p := range set {
+		if strings.HasSuffix(p, "*") && strings.HasPrefix(name, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// StripParams removes the parameters in set from raw's query string.
+// Remaining parameters keep their order and original encoding; links that
+// fail to parse are returned untouched.
+func StripParams(raw string, set map[string]struct{}) string {
+	if _, err := url.Parse(raw); err != nil {
+		return raw
+	}
+	base, frag, hasFrag := strings.Cut(raw, "#")
+	base, query, hasQuery := strings.Cut(base, "?")
+	if !hasQuery {
+		return raw
+	}
+	var kept []string
+	for _, pair := range strings.Split(query, "&") {
+		name, _, _ := strings.Cut(pair, "=")
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		if pair != "" && !stripParam(name, set) {
+			kept = append(kept, pair)
+		}
+	}
+	if len(kept) > 0 {
+		base += "?" + strings.Join(kept, "&")
+	}
+	if hasFrag {
+		base += "#" + frag
+	}
+	return base
+}
+
+// CanonicalScore ranks variants that share a dedup key: https beats http
+// and a bare host beats www.
+func CanonicalScore(raw string) int {
+	score := 0
+	if strings.HasPrefix(strings.ToLower(raw), "https://") {
+		score += 2
+	}
+	if h := HostOf(raw); !strings.HasPrefix(h, "www.") {
+		score++
+	}
+	return score
+}
+
+// UniqueByKey keeps the first occurrence of each key, in order, replacing
+// it with a later variant when that one is the better canonical form.
+func UniqueByKey(in []string, key func(string) string) []string {
+	idx := make(map[string]int, len(in))
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		if s == "" {
+			continue
+		}
+		k := key(s)
+		if i, ok := idx[k]; ok {
+			if s != out[i] && CanonicalScore(s) > CanonicalScore(out[i]) {
+				out[i] = s
+			}
+			continue
+		}
+		idx[k] = len(out)
+		out = append(out, s)
+	}
+	return out
+}
+
+// NormalizeHost lowercases h and strips any port and trailing dot.
+func NormalizeHost(h string) string {
+	if hh, _, err := net.SplitHostPort(h); err == nil {
+		h = hh
+	}
+	return strings.TrimSuffix(strings.ToLower(h), ".")
+}
+
+// RegisteredDomain returns the eTLD+1 of host (example.co.uk for
+// dev.example.co.uk), or the normalized host itself when it has none (IPs,
+// bare suffixes).
+func RegisteredDomain(host string) string {
+	host = ASCIIHost(NormalizeHost(host))
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	d, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return d
+}
+
+// InScope reports whether host belongs to the same registered domain as
+// the target, so that "evil.co.uk" is not kept for a target of
+// "example.co.uk". apex is RegisteredDomain(target).
+func InScope(host, target, apex string) bool {
+	if net.ParseIP(apex) != nil {
+		return host == apex
+	}
+	if RegisteredDomain(host) == apex {
+		return true
+	}
+	t := NormalizeHost(target)
+	return host == t || strings.HasSuffix(host, "."+t)
+}
+
+// RelativeHost trims the apex from host: dev.api.example.com -> dev.api.
+// The apex itself is returned as "@".
+func RelativeHost(host, apex string) string {
+	if host == apex {
+		return "@"
+	}
+	return strings.TrimSuffix(host, "."+apex)
+}
+
+// HostOf returns the lowercase hostname of raw without userinfo, port,
+// IPv6 brackets or trailing dot. Scheme-less input ("dev.example.com/x",
+// "//dev.example.com") is accepted.
+func HostOf(raw string) string {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case raw == "":
+		return ""
+	case strings.HasPrefix(raw, "//"):
+		raw = "http:" + raw
+	case !strings.Contains(raw, "://"):
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return ASCIIHost(strings.TrimSuffix(strings.ToLower(u.Hostname()), "."))
+}
+
+// NormalizeTarget checks that t is a usable search target and returns its
+// canonical form: surrounding space trimmed, lowercased, trailing dots
+// removed and Unicode labels in punycode. IPs and CIDR ranges are accepted
+// as is. Anything else must be a hostname with at least two labels of
+// letters, digits, hyphens and underscores.
+func NormalizeTarget(t string) (string, error) {
+	t = strings.TrimSpace(t)
+	if t == "" {
+		return "", errors.New("empty target")
+	}
+	if strings.IndexFunc(t, unicode.IsSpace) >= 0 {
+		return "", fmt.Errorf("invalid target %q: contains spaces", t)
+	}
+	if ip := net.ParseIP(t); ip != nil {
+		return ip.String(), nil
+	}
+	if _, n, err := net.ParseCIDR(t); err == nil {
+		return n.String(), nil
+	}
+	h := strings.TrimRight(strings.ToLower(t), ".")
+	if !isASCII(h) {
+		a, err := idna.Lookup.ToASCII(h)
+		if err != nil {
+			return "", fmt.Errorf("invalid target %q: %v", t, err)
+		}
+		h = a
+	}
+	if len(h) > 253 {
+		return "", fmt.Errorf("invalid target %q: longer than 253 characters", t)
+	}
+	labels := strings.Split(h, ".")
+	if len(labels) < 2 {
+		return "", fmt.Errorf("invalid target %q: not a domain name", t)
+	}
+	for _, l := range labels {
+		if l == "" || len(l) > 63 || l[0] == '-' || l[len(l)-1] == '-' {
+			return "", fmt.Errorf("invalid target %q: bad label %q", t, l)
+		}
+		for _, r := range l {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return "", fmt.Errorf("invalid target %q: bad character %q", t, r)
+			}
+		}
+	}
+	return h, nil
+}
+
+// ASCIIHost converts an internationalized host to its punycode form
+// (münchen.example.de -> xn--mnchen-3ya.example.de) so that Unicode and
+// ASCII variants compare equal. ASCII input is returned unchanged.
+func ASCIIHost(h string) string {
+	if isASCII(h) {
+		return h
+	}
+	a, err := idna.Lookup.ToASCII(strings.ToLower(h))
+	if err != nil {
+		return h
+	}
+	return a
+}
+
+// ASCIILink rewrites the host of an absolute link to its punycode form,
+// leaving the rest of the link byte-for-byte intact.
+func ASCIILink(l string) string {
+	if isASCII(l) {
+		return l
+	}
+	u, err := url.Parse(l)
+	if err != nil || u.Host == "" || isASCII(u.Host) {
+		return l
+	}
+	host := ASCIIHost(u.Hostname())
+	if p := u.Port(); p != "" {
+		host = net.JoinHostPort(host, p)
+	}
+	return strings.Replace(l, u.Host, host, 1)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package banshee
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Google Custom Search JSON API, the default engine.
+
+const (
+	googleAPIURL = "https://www.googleapis.com/customsearch/v1"
+	// DefaultCX is the Programmable Search Engine used when none is given.
+	DefaultCX = "759aed2f7b4be4b83"
+)
+
+type googleResponse struct {
+	Items []struct {
+		Link string `json:"link"`
+	} `json:"items"`
+	Error *googleError `json:"error"`
+}
+
+// googleError is the error object of a Google API response.
+type googleError struct {
+	Code    int    `json:"code"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Errors  []struct {
+		Reason  string `json:"reason"`
+		Domain  string `json:"domain"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Details []struct {
+		Reason   string            `json:"reason"`
+		Metadata map[string]string `json:"metadata"`
+	} `json:"details"`
+}
+
+// projectNumber matches the consumer a quota message names, as in "...
+// of service 'customsearch.googleapis.com' for consumer
+// 'project_number:123456789'".
+var projectNumber = regexp.MustCompile(`project_number:(\d+)`)
+
+// project returns the Cloud project whose quota the error blames, from
+// the ErrorInfo details or failing that the message, or "" when the error
+// doesn't name one. The Custom Search quota belongs to the project, so
+// every key created in it fails the same way.
+func (e *googleError) project() string {
+	for _, d := range e.Details {
+		if c, ok := strings.CutPrefix(d.Metadata["consumer"], "projects/"); ok && c != "" {
+			return c
+		}
+	}
+	if m := projectNumber.FindStringSubmatch(e.Message); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// kind classifies the error by its reasons. Google reports the daily quota
+// running out as dailyLimitExceeded, or as rateLimitExceeded with a
+// "per day" limit in the message; other rateLimitExceeded errors only mean
+// slowing down.
+func (e *googleError) kind() errKind {
+	msg := strings.ToLower(e.Message)
+	for _, r := range e.Errors {
+		switch r.Reason {
+		case "dailyLimitExceeded", "dailyLimitExceededUnreg", "quotaExceeded":
+			return errQuota
+		case "rateLimitExceeded", "userRateLimitExceeded":
+			if strings.Contains(msg, "per day") {
+				return errQuota
+			}
+			return errRate
+		case "keyInvalid", "keyExpired", "accessNotConfigured":
+			return errBadKey
+		case "invalid", "badRequest":
+			if strings.Contains(msg, "api key") {
+				return errBadKey // "API key not valid. Please pass a valid API key."
+			}
+			return errBadQuery
+		}
+	}
+	// no reasons given: fall back to the message
+	if strings.Contains(msg, "quota") {
+		return errQuota
+	}
+	return errOther
+}
+
+func (e *googleError) String() string {
+	s := e.Message
+	if e.Code != 0 {
+		s = strings.TrimSpace(fmt.Sprintf("%d %s", e.Code, e.Status)) + ": " + s
+	}
+	for _, r := range e.Errors {
+		if r.Reason != "" {
+			s += fmt.Sprintf(" (%s/%s)", r.Domain, r.Reason)
+			break
+		}
+	}
+	return s
+}
+
+// googleBaseURL returns the request URL builder for search engine cx: the
+// URL for one page, up to the query value.
+func googleBaseURL(cx string) func(key string, startIdx int) string {
+	return func(key string, startIdx int) string {
+		return fmt.Sprintf("%s?key=%s&cx=%s&start=%d&q=", googleAPIURL, url.QueryEscape(key), url.QueryEscape(cx), startIdx)
+	}
+}
+
+func decodeGoogle(body []byte) (links []string, apiErr *apiError, err error) {
+	var gr googleResponse
+	if err := json.Unmarshal(body, &gr); err != nil {
+		return nil, nil, fmt.Errorf("decode error: %w", err)
+	}
+	if e := gr.Error; e != nil && (e.Message != "" || e.Code != 0) {
+		ae := &apiError{msg: e.String(), kind: e.kind()}
+		if ae.kind == errQuota {
+			ae.project = e.project()
+		}
+		return nil, ae, nil
+	}
+	for _, it := range gr.Items {
+		links = append(links, it.Link)
+	}
+	return links, nil, nil
+}
@@ -0,0 +1,393 @@
+package banshee
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// KeyPool is a provider's set of API keys, shared by every search (and
+// every worker) using the provider, so that a key exhausted by one is
+// skipped by all. It also counts the requests issued with each key. It is
+// safe for concurrent use.
+//
+// An exhausted key is not retired for good: Readmit makes it usable again
+// once its quota should have been renewed, see SetCooldown.
+type KeyPool struct {
+	mu        sync.Mutex
+	keys      []string
+	exhausted map[string]time.Time // key -> when it is usable again
+	requests  map[string]int
+	today     map[string]int // requests since the last daily quota reset
+	todayEnds time.Time      // the next reset, when today starts over
+	cooldown  time.Duration
+	interval  time.Duration
+	last      map[string]time.Time // key -> when it was last handed out
+
+	// project quota tracking, see MarkProjectExhausted
+	project  map[string]string // key -> project whose quota it ran out of
+	proven   map[string]bool   // keys answered since a project quota ran out
+	streak   int               // consecutive failures on streakOf's quota
+	streakOf string
+}
+
+// ProjectStreak is how many keys in a row must run out of the same Cloud
+// project's quota before the pool stops trying keys it knows nothing
+// about: they are most likely in that project too.
+const ProjectStreak = 3
+
+// NewKeyPool returns a pool holding keys.
+func NewKeyPool(keys []string) *KeyPool {
+	return &KeyPool{
+		keys:      keys,
+		exhausted: make(map[string]time.Time),
+		requests:  make(map[string]int),
+		today:     make(map[string]int),
+		last:      make(map[string]time.Time),
+		project:   make(map[string]string),
+		proven:    make(map[string]bool),
+	}
+}
+
+// SetInterval sets the least time between two requests with the same key,
+// for APIs with per-key rate limits. Acquire then prefers keys that are
+// ready, so the pool as a whole goes faster the more keys it has.
+func (kp *KeyPool) SetInterval(d time.Duration) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.interval = d
+}
+
+// SetCooldown sets how long an exhausted key stays out of rotation. With 0,
+// the default, it is readmitted at the next midnight Pacific Time, when
+// Google's daily quotas reset.
+func (kp *KeyPool) SetCooldown(d time.Duration) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.cooldown = d
+}
+
+var pacific = func() *time.Location {
+	if loc, err := time.LoadLocation("America/Los_Angeles"); err == nil {
+		return loc
+	}
+	return time.FixedZone("PST", -8*60*60) // no tzdata; off by an hour in summer
+}()
+
+// nextQuotaReset is the first midnight Pacific Time after t.
+func nextQuotaReset(t time.Time) time.Time {
+	t = t.In(pacific)
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, pacific)
+}
+
+// Readmit returns the exhausted keys whose cooldown is over at now to the
+// rotation, and returns them.
+func (kp *KeyPool) Readmit(now time.Time) []string {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	var back []string
+	for _, k := range kp.keys {
+		if until, ex := kp.exhausted[k]; ex && !now.Before(until) {
+			delete(kp.exhausted, k)
+			delete(kp.last, k)
+			delete(kp.project, k)
+			back = append(back, k)
+		}
+	}
+	return back
+}
+
+// Replace swaps the pool's keys for keys, for reloading a changed key file.
+// Keys in both keep their exhaustion state and request counts; searches in
+// flight with a removed key finish normally. It returns how many keys were
+// added and removed.
+func (kp *KeyPool) Replace(keys []string) (added, removed int) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	old := make(map[string]bool, len(kp.keys))
+	for _, k := range kp.keys {
+		old[k] = true
+	}
+	keep := make(map[string]bool, len(keys))
+	var next []string
+	for _, k := range keys {
+		if keep[k] {
+			continue
+		}
+		keep[k] = true
+		next = append(next, k)
+		if !old[k] {
+			added++
+		}
+	}
+	for k := range old {
+		if !keep[k] {
+			removed++
+			delete(kp.exhausted, k)
+			delete(kp.last, k)
+			delete(kp.project, k)
+			delete(kp.proven, k)
+		}
+	}
+	kp.keys = next
+	return added, removed
+}
+
+// usableOther reports whether a usable key other than key is left.
+func (kp *KeyPool) usableOther(key string) bool {
+	for _, k := range kp.keys {
+		if _, ex := kp.exhausted[k]; !ex && k != key {
+			return true
+		}
+	}
+	return false
+}
+
+type avoidKeyKey struct{}
+
+// WithoutKey returns a context that makes Search pick another key than
+// key if it has one, for retrying a request whose answer may have been
+// the key's fault.
+func WithoutKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, avoidKeyKey{}, key)
+}
+
+// RecordSuccess notes that key got an answer, which sets it apart from
+// the keys of a project whose quota ran out.
+func (kp *KeyPool) RecordSuccess(key string) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.streak = 0
+	if len(kp.project) > 0 {
+		kp.proven[key] = true
+	}
+}
+
+// MarkProjectExhausted flags key as over quota like MarkExhausted, for an
+// error that blames the quota of Cloud project rather than the key's own.
+// Keys already seen failing with that project go with it. Once
+// ProjectStreak keys in a row failed on the same project, every key that
+// hasn't answered since a project quota first ran out is retired too,
+// rather than spending a request on each to find out; keys that did answer
+// belong to other projects and stay. It reports whether this call marked
+// key, and the other keys it retired.
+func (kp *KeyPool) MarkProjectExhausted(key, project string) (marked bool, retired []string) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	_, done := kp.exhausted[key]
+	if done {
+		return false, nil
+	}
+	until := kp.quotaReset()
+	kp.exhausted[key] = until
+	kp.project[key] = project
+	delete(kp.proven, key)
+	if kp.streakOf == project {
+		kp.streak++
+	} else {
+		kp.streak, kp.streakOf = 1, project
+	}
+	for _, k := range kp.keys {
+		if _, ex := kp.exhausted[k]; ex {
+			continue
+		}
+		if kp.project[k] == project || kp.streak >= ProjectStreak && !kp.proven[k] {
+			kp.exhausted[k] = until
+			kp.project[k] = project
+			retired = append(retired, k)
+		}
+	}
+	return true, retired
+}
+
+// RecordRequest counts one API request made with key.
+func (kp *KeyPool) RecordRequest(key string) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.requests[key]++
+	kp.rollDay(time.Now())
+	kp.today[key]++
+}
+
+// rollDay starts the per-key counts of the day over once the daily quotas
+// have been reset.
+func (kp *KeyPool) rollDay(now time.Time) {
+	if now.Before(kp.todayEnds) {
+		return
+	}
+	clear(kp.today)
+	kp.todayEnds = nextQuotaReset(now)
+}
+
+// KeyUsage is how much one key of a pool has been used.
+type KeyUsage struct {
+	Key       string
+	Requests  int // since the pool was created
+	Today     int // since the last daily quota reset, midnight Pacific Time
+	Exhausted bool
+}
+
+// Usage reports the use of every key in the pool, in pool order.
+func (kp *KeyPool) Usage() []KeyUsage {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	now := time.Now()
+	kp.rollDay(now)
+	out := make([]KeyUsage, len(kp.keys))
+	for i, k := range kp.keys {
+		until, ex := kp.exhausted[k]
+		out[i] = KeyUsage{Key: k, Requests: kp.requests[k], Today: kp.today[k], Exhausted: ex && now.Before(until)}
+	}
+	return out
+}
+
+// Requests is the number of API requests made with all keys.
+func (kp *KeyPool) Requests() int {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	n := 0
+	for _, v := range kp.requests {
+		n += v
+	}
+	return n
+}
+
+// KeyRequests is the number of API requests made with each key that was
+// used.
+func (kp *KeyPool) KeyRequests() map[string]int {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	out := make(map[string]int, len(kp.requests))
+	for k, v := range kp.requests {
+		out[k] = v
+	}
+	return out
+}
+
+// Usable is the number of keys not exhausted, or due to be readmitted.
+func (kp *KeyPool) Usable() int {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	n := 0
+	now := time.Now()
+	for _, k := range kp.keys {
+		if until, ex := kp.exhausted[k]; !ex || !now.Before(until) {
+			n++
+		}
+	}
+	return n
+}
+
+// Len is the number of keys in the pool.
+func (kp *KeyPool) Len() int {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	return len(kp.keys)
+}
+
+// Next picks a usable key, or returns ErrNoKeys. It ignores the interval
+// set by SetInterval, see Acquire.
+func (kp *KeyPool) Next() (string, error) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	key, _, err := kp.pick(time.Time{}, "")
+	return key, err
+}
+
+// Acquire picks a usable key that is ready for another request, waiting
+// for the first one to become ready when none is. A key set with
+// WithoutKey is only picked when it is the last usable one. It returns
+// ErrNoKeys when no key is usable, or the context's error if it is done
+// first.
+func (kp *KeyPool) Acquire(ctx context.Context) (string, error) {
+	avoid, _ := ctx.Value(avoidKeyKey{}).(string)
+	for {
+		kp.mu.Lock()
+		key, wait, err := kp.pick(time.Now(), avoid)
+		kp.mu.Unlock()
+		if err != nil || wait <= 0 {
+			return key, err
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return "", ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// pick chooses among the usable keys that are ready at now and records
+// the choice. With a zero now, or no interval, every usable key is ready.
+// When none is, it returns how long until the first one is instead.
+// avoid is skipped unless it is the only usable key.
+func (kp *KeyPool) pick(now time.Time, avoid string) (string, time.Duration, error) {
+	available := make([]string, 0, len(kp.keys))
+	var wait time.Duration = -1
+	skip := avoid != "" && kp.usableOther(avoid)
+	for _, k := range kp.keys {
+		if _, ex := kp.exhausted[k]; ex {
+			continue
+		}
+		if skip && k == avoid {
+			continue
+		}
+		if !now.IsZero() && kp.interval > 0 {
+			if w := kp.last[k].Add(kp.interval).Sub(now); w > 0 {
+				if wait < 0 || w < wait {
+					wait = w
+				}
+				continue
+			}
+		}
+		available = append(available, k)
+	}
+	if len(available) == 0 {
+		if wait > 0 {
+			return "", wait, nil
+		}
+		return "", 0, ErrNoKeys
+	}
+	// Rotate pseudo-randomly by time
+	idx := int(time.Now().UnixNano()) % len(available)
+	key := available[idx]
+	kp.last[key] = time.Now()
+	return key, 0, nil
+}
+
+// MarkInvalid retires key for good, for keys the API rejects. It reports
+// whether this call did the marking.
+func (kp *KeyPool) MarkInvalid(key string) bool {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	if until, ok := kp.exhausted[key]; ok && until.Equal(never) {
+		return false
+	}
+	kp.exhausted[key] = never
+	return true
+}
+
+// never is the readmission time of an invalid key.
+var never = time.Unix(1<<62, 0)
+
+// MarkExhausted flags key as over quota. It reports whether this call did
+// the marking, so concurrent workers hitting the same quota log it once.
+func (kp *KeyPool) MarkExhausted(key string) bool {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	if _, ok := kp.exhausted[key]; ok {
+		return false
+	}
+	kp.exhausted[key] = kp.quotaReset()
+	return true
+}
+
+// quotaReset is when a key exhausted now is readmitted.
+func (kp *KeyPool) quotaReset() time.Time {
+	now := time.Now()
+	if kp.cooldown > 0 {
+		return now.Add(kp.cooldown)
+	}
+	return nextQuotaReset(now)
+}
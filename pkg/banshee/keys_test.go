@@ -0,0 +1,68 @@
+package banshee
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// The pool is shared by every worker of a run; run with -race.
+func TestKeyPoolConcurrent(t *testing.T) {
+	keys := make([]string, 8)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	kp := NewKeyPool(keys)
+	var marked [8]atomic.Int32
+	var sent atomic.Int32
+	var wg sync.WaitGroup
+	for w := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				k, err := kp.Acquire(context.Background())
+				if err != nil {
+					return // every key ran out
+				}
+				kp.RecordRequest(k)
+				sent.Add(1)
+				if (w+i)%50 == 49 {
+					var n int
+					fmt.Sscanf(k, "key-%d", &n)
+					if kp.MarkExhausted(k) {
+						marked[n].Add(1)
+					}
+				}
+				kp.Usable()
+				kp.Requests()
+			}
+		}()
+	}
+	wg.Wait()
+	if got := kp.Requests(); got != int(sent.Load()) {
+		t.Errorf("Requests() = %d, want %d", got, sent.Load())
+	}
+	total := 0
+	for _, n := range kp.KeyRequests() {
+		total += n
+	}
+	if total != int(sent.Load()) {
+		t.Errorf("KeyRequests() adds up to %d, want %d", total, sent.Load())
+	}
+	exhausted := 0
+	for i := range marked {
+		switch marked[i].Load() {
+		case 0:
+		case 1:
+			exhausted++
+		default:
+			t.Errorf("%s was reported exhausted %d times, want once", keys[i], marked[i].Load())
+		}
+	}
+	if got := kp.Usable(); got != len(keys)-exhausted {
+		t.Errorf("Usable() = %d, want %d", got, len(keys)-exhausted)
+	}
+}
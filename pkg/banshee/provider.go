@@ -0,0 +1,367 @@
+package banshee
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultUserAgent is sent with every request made by this package.
+const DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36 GLS/100.10.9939.100"
+
+// Engines lists the engine names NewProvider accepts.
+var Engines = []string{"google", "serpapi", "yandex"}
+
+// Provider is a search backend. The caller decides what to query, pages
+// through results and paces requests; a provider turns one query into the
+// links on one page, picking and retiring its own API keys.
+type Provider interface {
+	Name() string
+	// Rewrite adapts a Google-syntax query to the engine's operators.
+	Rewrite(q string) string
+	Search(ctx context.Context, q Query) ([]string, error)
+	QuotaState() QuotaState
+}
+
+// Query is one page of one search. Start is the 1-based index of the first
+// result on the page.
+type Query struct {
+	Text  string
+	Start int
+}
+
+// QuotaState describes a provider's key pool.
+type QuotaState struct {
+	Requests int // requests made so far
+	Usable   int // keys not exhausted
+	Keys     int
+}
+
+var (
+	// ErrNoKeys is returned once every key of a provider is exhausted.
+	ErrNoKeys = errors.New("no available API keys left. All keys have exceeded their quota")
+	// ErrKeyExhausted is wrapped by the error of a search whose key ran
+	// out of quota; the key is retired and the search can be retried.
+	ErrKeyExhausted = errors.New("API key exhausted")
+	// ErrKeyInvalid is wrapped by the error of a search whose key the API
+	// rejected; the key is retired for good and the search can be retried.
+	ErrKeyInvalid = errors.New("API key invalid")
+	// ErrRateLimited is wrapped by the error of a search refused for
+	// coming too fast; the key stays usable.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrInvalidQuery is wrapped by the error of a search whose query the
+	// API rejected. Retrying it, with any key, is pointless.
+	ErrInvalidQuery = errors.New("invalid query")
+	// ErrBadResponse is wrapped by the error of a search answered with a
+	// body the engine's decoder can't read, typically an HTML page from a
+	// proxy or a consent/error page. It says nothing about the key, and
+	// the search can be retried as is.
+	ErrBadResponse = errors.New("unexpected response")
+	// ErrRequestTimeout is wrapped by the error of a search that got no
+	// answer in time, usually a stalled proxy or connection. It says
+	// nothing about the key, and the search can be retried as is.
+	ErrRequestTimeout = errors.New("request timed out")
+)
+
+// BadResponseError describes an unreadable response body. Its message is a
+// one-line summary; Body holds the full body for debugging.
+type BadResponseError struct {
+	Format string // what the decoder expected: JSON or XML
+	Status int
+	Body   []byte
+	Err    error // the decode error
+}
+
+func (e *BadResponseError) Error() string {
+	size := fmt.Sprintf("%dB", len(e.Body))
+	if len(e.Body) >= 1024 {
+		size = fmt.Sprintf("%dKB", (len(e.Body)+512)/1024)
+	}
+	return fmt.Sprintf("non-%s response, status %d, %s %s body", e.Format, e.Status, size, sniffBody(e.Body))
+}
+
+func (e *BadResponseError) Unwrap() []error { return []error{ErrBadResponse, e.Err} }
+
+// sniffBody names the kind of content in b for BadResponseError.
+func sniffBody(b []byte) string {
+	ct := http.DetectContentType(b)
+	switch {
+	case len(b) == 0:
+		return "empty"
+	case strings.HasPrefix(ct, "text/html"):
+		return "HTML"
+	case strings.HasPrefix(ct, "text/xml"):
+		return "XML"
+	case strings.HasPrefix(ct, "text/plain"):
+		if t := bytes.TrimSpace(b); len(t) > 0 && (t[0] == '{' || t[0] == '[') {
+			return "JSON"
+		}
+		return "text"
+	}
+	return "binary"
+}
+
+// apiError is an error reported in an API response body.
+type apiError struct {
+	msg  string
+	kind errKind
+	// project is set for errQuota when the error names the Cloud project
+	// whose quota ran out, which all of that project's keys share.
+	project string
+}
+
+type errKind int
+
+const (
+	errOther    errKind = iota
+	errQuota            // the key is out of quota
+	errRate             // too many requests for now
+	errBadKey           // the key is invalid or not allowed to search
+	errBadQuery         // the query itself was rejected
+)
+
+// Event reports a provider action, for logging.
+type Event struct {
+	Kind    string // "query", "key_exhausted", "key_invalid", "key_readmitted" or "project_exhausted"
+	Engine  string
+	Key     string
+	Query   string   // "query" only
+	Start   int      // "query" only
+	Error   string   // "key_exhausted", "key_invalid" and "project_exhausted": the API's message
+	Project string   // "key_exhausted" and "project_exhausted": the Cloud project, when known
+	Retired []string // "project_exhausted": the other keys retired with Key
+}
+
+// SearchInfo receives what a Search call did; see WithSearchInfo.
+type SearchInfo struct {
+	Key    string // key the request was sent with, "" if none was left
+	Status int    // HTTP status code, 0 when no response arrived
+}
+
+type searchInfoKey struct{}
+
+// WithSearchInfo returns a context that makes Search fill in info, for
+// callers that keep their own record of every request.
+func WithSearchInfo(ctx context.Context, info *SearchInfo) context.Context {
+	return context.WithValue(ctx, searchInfoKey{}, info)
+}
+
+// ProviderOptions configures NewProvider.
+type ProviderOptions struct {
+	// HTTPClient sends the API requests; http.DefaultClient when nil.
+	HTTPClient *http.Client
+	// OnEvent, when set, is called for every query sent and every key
+	// retired or readmitted.
+	OnEvent func(Event)
+	// CX is the Google Programmable Search Engine ID; DefaultCX when
+	// empty. Other engines ignore it.
+	CX string
+	// KeyCooldown is passed to the key pool's SetCooldown.
+	KeyCooldown time.Duration
+	// KeyInterval is passed to the key pool's SetInterval.
+	KeyInterval time.Duration
+	// RequestTimeout bounds each API request on top of the client's own
+	// timeout; 0 leaves it to the client.
+	RequestTimeout time.Duration
+}
+
+// apiProvider is a Provider for a key-authenticated HTTP API. The engines
+// only differ in URL layout, response decoding, the wording of their quota
+// errors and query syntax.
+type apiProvider struct {
+	name    string
+	keys    *KeyPool
+	client  *http.Client
+	timeout time.Duration
+	onEvent func(Event)
+
+	baseURL func(key string, startIdx int) string // up to the query value
+	decode  func(body []byte) (links []string, apiErr *apiError, err error)
+	format  string                // body format decode reads, for BadResponseError
+	rewrite func(q string) string // nil when the engine takes Google syntax
+}
+
+// NewProvider returns the provider for engine name (one of Engines) using
+// keys. Yandex keys are "user:key" pairs.
+func NewProvider(name string, keys []string, opts ProviderOptions) (Provider, error) {
+	p := &apiProvider{name: name, keys: NewKeyPool(keys), client: opts.HTTPClient, onEvent: opts.OnEvent,
+		timeout: opts.RequestTimeout}
+	if p.client == nil {
+		p.client = http.DefaultClient
+	}
+	if p.onEvent == nil {
+		p.onEvent = func(Event) {}
+	}
+	p.keys.SetCooldown(opts.KeyCooldown)
+	p.keys.SetInterval(opts.KeyInterval)
+	switch name {
+	case "google":
+		cx := opts.CX
+		if cx == "" {
+			cx = DefaultCX
+		}
+		p.baseURL, p.decode, p.format = googleBaseURL(cx), decodeGoogle, "JSON"
+	case "serpapi":
+		p.baseURL, p.decode, p.format = serpBaseURL, decodeSerp, "JSON"
+	case "yandex":
+		p.baseURL, p.decode, p.format = yandexBaseURL, decodeYandex, "XML"
+		p.rewrite = adaptYandexQuery
+	default:
+		return nil, fmt.Errorf("unknown engine %q", name)
+	}
+	return p, nil
+}
+
+// EndpointURL returns the address of engine name's search API, without
+// query parameters, or "" for an unknown engine.
+func EndpointURL(name string) string {
+	switch name {
+	case "google":
+		return googleAPIURL
+	case "serpapi":
+		return serpAPIURL
+	case "yandex":
+		return yandexAPIURL
+	}
+	return ""
+}
+
+func (p *apiProvider) Name() string { return p.name }
+
+func (p *apiProvider) Rewrite(q string) string {
+	if p.rewrite == nil {
+		return q
+	}
+	return p.rewrite(q)
+}
+
+// Search sends q with a random usable key. A quota error retires the key
+// and is returned wrapping ErrKeyExhausted.
+func (p *apiProvider) Search(ctx context.Context, q Query) ([]string, error) {
+	for _, k := range p.keys.Readmit(time.Now()) {
+		p.onEvent(Event{Kind: "key_readmitted", Engine: p.name, Key: k})
+	}
+	key, err := p.keys.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.onEvent(Event{Kind: "query", Engine: p.name, Key: key, Query: q.Text, Start: q.Start})
+	p.keys.RecordRequest(key)
+	rctx := ctx
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		rctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	body, status, err := HTTPGet(rctx, p.client, p.baseURL(key, q.Start)+url.QueryEscape(q.Text))
+	if info, ok := ctx.Value(searchInfoKey{}).(*SearchInfo); ok {
+		info.Key, info.Status = key, status
+	}
+	if err != nil {
+		if ctx.Err() == nil && isTimeout(err) {
+			return nil, fmt.Errorf("%w: %v", ErrRequestTimeout, err)
+		}
+		return nil, err
+	}
+	links, apiErr, err := p.decode(body)
+	if err != nil {
+		return nil, &BadResponseError{Format: p.format, Status: status, Body: body, Err: err}
+	}
+	if apiErr != nil {
+		switch apiErr.kind {
+		case errQuota:
+			if apiErr.project == "" {
+				if p.keys.MarkExhausted(key) {
+					p.onEvent(Event{Kind: "key_exhausted", Engine: p.name, Key: key, Error: apiErr.msg})
+				}
+				return nil, fmt.Errorf("%w: %s", ErrKeyExhausted, apiErr.msg)
+			}
+			if marked, retired := p.keys.MarkProjectExhausted(key, apiErr.project); marked {
+				p.onEvent(Event{Kind: "key_exhausted", Engine: p.name, Key: key, Error: apiErr.msg, Project: apiErr.project})
+				if len(retired) > 0 {
+					p.onEvent(Event{Kind: "project_exhausted", Engine: p.name, Key: key, Error: apiErr.msg,
+						Project: apiErr.project, Retired: retired})
+				}
+			}
+			return nil, fmt.Errorf("%w: project %s quota exhausted: %s", ErrKeyExhausted, apiErr.project, apiErr.msg)
+		case errBadKey:
+			if p.keys.MarkInvalid(key) {
+				p.onEvent(Event{Kind: "key_invalid", Engine: p.name, Key: key, Error: apiErr.msg})
+			}
+			return nil, fmt.Errorf("%w: %s", ErrKeyInvalid, apiErr.msg)
+		case errRate:
+			return nil, fmt.Errorf("%w: %s", ErrRateLimited, apiErr.msg)
+		case errBadQuery:
+			return nil, fmt.Errorf("%w: %s", ErrInvalidQuery, apiErr.msg)
+		}
+		return nil, errors.New(apiErr.msg)
+	}
+	p.keys.RecordSuccess(key)
+	return links, nil
+}
+
+// KeyReloader is implemented by providers whose keys can be replaced while
+// searches run.
+type KeyReloader interface {
+	// ReloadKeys swaps in keys, see KeyPool.Replace.
+	ReloadKeys(keys []string) (added, removed int)
+}
+
+func (p *apiProvider) ReloadKeys(keys []string) (added, removed int) {
+	return p.keys.Replace(keys)
+}
+
+// KeyCounter is implemented by providers that count requests per key.
+type KeyCounter interface {
+	// KeyRequests returns the requests made with each key, see
+	// KeyPool.KeyRequests.
+	KeyRequests() map[string]int
+	// KeyUsage describes each current key, see KeyPool.Usage.
+	KeyUsage() []KeyUsage
+}
+
+func (p *apiProvider) KeyRequests() map[string]int {
+	return p.keys.KeyRequests()
+}
+
+func (p *apiProvider) KeyUsage() []KeyUsage {
+	return p.keys.Usage()
+}
+
+func (p *apiProvider) QuotaState() QuotaState {
+	return QuotaState{Requests: p.keys.Requests(), Usable: p.keys.Usable(), Keys: p.keys.Len()}
+}
+
+// isTimeout reports whether err is a request's own deadline or the
+// client's timeout running out.
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &ne) && ne.Timeout()
+}
+
+// HTTPGet fetches u with DefaultUserAgent and returns the body and status
+// code.
+func HTTPGet(ctx context.Context, cl *http.Client, u string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	return body, resp.StatusCode, nil
+}
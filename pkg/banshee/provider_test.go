@@ -0,0 +1,256 @@
+package banshee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// redirect sends every request to a test server, whatever its URL.
+type redirect struct{ to *url.URL }
+
+func (r redirect) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = r.to.Scheme, r.to.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// testProvider returns engine's provider with keys, talking to h.
+func testProvider(t *testing.T, engine string, keys []string, h http.HandlerFunc, opts ProviderOptions) Provider {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	to, _ := url.Parse(srv.URL)
+	opts.HTTPClient = &http.Client{Transport: redirect{to}}
+	p, err := NewProvider(engine, keys, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// pageBody is a response with n links for page, in engine's format.
+func pageBody(engine string, page, n int) string {
+	var b strings.Builder
+	for i := range n {
+		u := fmt.Sprintf("https://example.com/%d/%d", page, i)
+		switch engine {
+		case "google", "serpapi":
+			fmt.Fprintf(&b, `{"link":%q},`, u)
+		case "yandex":
+			fmt.Fprintf(&b, "<group><doc><url>%s</url></doc></group>", u)
+		}
+	}
+	items := strings.TrimSuffix(b.String(), ",")
+	switch engine {
+	case "google":
+		return `{"items":[` + items + `]}`
+	case "serpapi":
+		return `{"organic_results":[` + items + `]}`
+	}
+	return "<yandexsearch><response><results><grouping>" + items + "</grouping></results></response></yandexsearch>"
+}
+
+// Each engine is asked for the page of Query.Start in its own terms, with
+// the query as given and the key, and its links are returned in order.
+func TestProviderPages(t *testing.T) {
+	tests := []struct {
+		engine, key      string
+		pageParam, qName string
+		page             func(start int) int // the pageParam value for start
+	}{
+		{"google", "gkey", "start", "q", func(s int) int { return s }},
+		{"serpapi", "skey", "start", "q", func(s int) int { return s - 1 }},
+		{"yandex", "user:ykey", "page", "query", func(s int) int { return (s - 1) / 10 }},
+	}
+	const query = `site:example.com inurl:"login" -site:www.example.com`
+	for _, tt := range tests {
+		t.Run(tt.engine, func(t *testing.T) {
+			p := testProvider(t, tt.engine, []string{tt.key}, func(w http.ResponseWriter, r *http.Request) {
+				v := r.URL.Query()
+				if got := v.Get(tt.qName); got != query {
+					t.Errorf("%s = %q, want %q", tt.qName, got, query)
+				}
+				if !strings.Contains(r.URL.RawQuery, strings.TrimPrefix(tt.key, "user:")) {
+					t.Errorf("request %s doesn't carry the key", r.URL)
+				}
+				var page int
+				fmt.Sscan(v.Get(tt.pageParam), &page)
+				fmt.Fprint(w, pageBody(tt.engine, page, 3))
+			}, ProviderOptions{})
+			for _, start := range []int{1, 11, 21} {
+				links, err := p.Search(context.Background(), Query{Text: query, Start: start})
+				if err != nil {
+					t.Fatal(err)
+				}
+				page := tt.page(start)
+				want := []string{
+					fmt.Sprintf("https://example.com/%d/0", page),
+					fmt.Sprintf("https://example.com/%d/1", page),
+					fmt.Sprintf("https://example.com/%d/2", page),
+				}
+				if !slices.Equal(links, want) {
+					t.Errorf("start %d: links = %q, want %q", start, links, want)
+				}
+			}
+			if qs := p.QuotaState(); qs.Requests != 3 || qs.Usable != 1 {
+				t.Errorf("QuotaState = %+v, want 3 requests and the key usable", qs)
+			}
+		})
+	}
+}
+
+// API errors map to the package's errors, and retire the key only when it
+// is the key's fault.
+func TestProviderErrors(t *testing.T) {
+	googleErr := func(code int, reason, msg string) string {
+		return fmt.Sprintf(`{"error":{"code":%d,"message":%q,"errors":[{"reason":%q,"domain":"usageLimits"}]}}`, code, msg, reason)
+	}
+	yandexErr := func(code int, msg string) string {
+		return fmt.Sprintf(`<yandexsearch><response><error code="%d">%s</error></response></yandexsearch>`, code, msg)
+	}
+	tests := []struct {
+		name, engine string
+		status       int
+		body         string
+		want         error // nil for a search that succeeds
+		usable       int   // of two keys, after the search
+	}{
+		{"google daily quota", "google", 429, googleErr(429, "dailyLimitExceeded", "Quota exceeded."), ErrKeyExhausted, 1},
+		{"google per-day rate limit", "google", 429, googleErr(429, "rateLimitExceeded", "Queries per day exceeded."), ErrKeyExhausted, 1},
+		{"google project quota", "google", 429, googleErr(429, "rateLimitExceeded",
+			"Quota exceeded for quota metric 'Queries' and limit 'Queries per day' of service 'customsearch.googleapis.com' for consumer 'project_number:123'."),
+			ErrKeyExhausted, 1},
+		{"google rate limit", "google", 429, googleErr(429, "rateLimitExceeded", "Queries per minute exceeded."), ErrRateLimited, 2},
+		{"google invalid key", "google", 400, googleErr(400, "badRequest", "API key not valid. Please pass a valid API key."), ErrKeyInvalid, 1},
+		{"google expired key", "google", 400, googleErr(400, "keyExpired", "API key expired."), ErrKeyInvalid, 1},
+		{"google bad query", "google", 400, googleErr(400, "invalid", "Invalid Value"), ErrInvalidQuery, 2},
+		{"google HTML page", "google", 200, "<html><body>Sorry...</body></html>", ErrBadResponse, 2},
+		{"google no results", "google", 200, `{"searchInformation":{"totalResults":"0"}}`, nil, 2},
+		{"serpapi out of searches", "serpapi", 429, `{"error":"Your account has run out of searches."}`, ErrKeyExhausted, 1},
+		{"serpapi no results", "serpapi", 200, `{"error":"Google hasn't returned any results for this query."}`, nil, 2},
+		{"serpapi truncated", "serpapi", 200, `{"organic_results":[`, ErrBadResponse, 2},
+		{"yandex daily limit", "yandex", 200, yandexErr(32, "Limit exceeded"), ErrKeyExhausted, 1},
+		{"yandex hourly limit", "yandex", 200, yandexErr(55, "Hourly limit exceeded"), ErrKeyExhausted, 1},
+		{"yandex no results", "yandex", 200, yandexErr(15, "Sorry, there are no results"), nil, 2},
+		{"yandex JSON", "yandex", 200, `{"error":"bad gateway"}`, ErrBadResponse, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			p := testProvider(t, tt.engine, []string{"u:k1", "u:k2"}, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}, ProviderOptions{OnEvent: func(e Event) { events = append(events, e.Kind) }})
+			links, err := p.Search(context.Background(), Query{Text: "site:example.com", Start: 1})
+			if tt.want == nil {
+				if err != nil || len(links) != 0 {
+					t.Errorf("Search = %q, %v; want no links and no error", links, err)
+				}
+			} else if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if got := p.QuotaState().Usable; got != tt.usable {
+				t.Errorf("%d usable keys, want %d (events %q)", got, tt.usable, events)
+			}
+		})
+	}
+}
+
+// The body of an unreadable response is kept for debugging, with a one-line
+// summary as the message.
+func TestProviderBadResponse(t *testing.T) {
+	p := testProvider(t, "google", []string{"k"}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, "<html><head><title>502 Bad Gateway</title></head></html>")
+	}, ProviderOptions{})
+	_, err := p.Search(context.Background(), Query{Text: "site:example.com", Start: 1})
+	var bad *BadResponseError
+	if !errors.As(err, &bad) {
+		t.Fatalf("err = %v, want a BadResponseError", err)
+	}
+	if bad.Status != http.StatusBadGateway || !strings.Contains(string(bad.Body), "502 Bad Gateway") {
+		t.Errorf("BadResponseError = status %d, body %q", bad.Status, bad.Body)
+	}
+	if want := "non-JSON response, status 502, 56B HTML body"; err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
+
+// Once every key is out of quota, searches fail with ErrNoKeys without
+// sending a request.
+func TestProviderKeysRunOut(t *testing.T) {
+	var requests int
+	p := testProvider(t, "serpapi", []string{"k1", "k2"}, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"error":"Your account has run out of searches."}`)
+	}, ProviderOptions{})
+	for range 2 {
+		if _, err := p.Search(context.Background(), Query{Text: "site:example.com", Start: 1}); !errors.Is(err, ErrKeyExhausted) {
+			t.Fatalf("err = %v, want ErrKeyExhausted", err)
+		}
+	}
+	if _, err := p.Search(context.Background(), Query{Text: "site:example.com", Start: 1}); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("err = %v, want ErrNoKeys", err)
+	}
+	if requests != 2 {
+		t.Errorf("sent %d requests, want 2", requests)
+	}
+}
+
+// A request left unanswered past RequestTimeout fails with
+// ErrRequestTimeout; the caller cancelling it does not.
+func TestProviderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	p := testProvider(t, "google", []string{"k"}, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}, ProviderOptions{RequestTimeout: 50 * time.Millisecond})
+	defer close(release)
+	_, err := p.Search(context.Background(), Query{Text: "site:example.com", Start: 1})
+	if !errors.Is(err, ErrRequestTimeout) {
+		t.Errorf("err = %v, want ErrRequestTimeout", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = p.Search(ctx, Query{Text: "site:example.com", Start: 1})
+	if errors.Is(err, ErrRequestTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the caller's context.DeadlineExceeded", err)
+	}
+	if got := p.QuotaState().Usable; got != 1 {
+		t.Errorf("%d usable keys, want the key kept", got)
+	}
+}
+
+// A built query reaches every engine as it was built: escaped once, with
+// &, #, + and quotes intact.
+func TestProviderQueryEscaping(t *testing.T) {
+	q := BuildQueries(QuerySpec{Target: "Example.com.", Dork: `inurl:"redirect=" intext:"a&b" #top c++ 100%`})[0].Query
+	for engine, param := range map[string]string{"google": "q", "serpapi": "q", "yandex": "query"} {
+		t.Run(engine, func(t *testing.T) {
+			p := testProvider(t, engine, []string{"u:k"}, func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query().Get(param); got != q {
+					t.Errorf("%s = %q, want %q", param, got, q)
+				}
+				if strings.ContainsAny(r.URL.RawQuery, " #\"") {
+					t.Errorf("raw query %q isn't escaped", r.URL.RawQuery)
+				}
+				fmt.Fprint(w, pageBody(engine, 0, 1))
+			}, ProviderOptions{})
+			if _, err := p.Search(context.Background(), Query{Text: q, Start: 1}); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
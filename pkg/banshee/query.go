@@ -0,0 +1,249 @@
+package banshee
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Request is one query to send for every page, with the term it was built
+// from (the extension, dictionary word, content string or dork).
+type Request struct {
+	Term  string
+	Query string
+}
+
+// QuerySpec describes the searches of one mode against one target. The
+// first non-empty of Dork, Extension, Terms and Contents selects the mode;
+// with none of them the target's pages are listed.
+type QuerySpec struct {
+	Target string
+	// Subdomains searches *.target instead of the target alone.
+	Subdomains bool
+	// Exclusions is appended to every query, see ExclusionQuery.
+	Exclusions string
+
+	Dork      string
+	Extension string   // filetype/ext search
+	Terms     []string // inurl: search, one query per term
+	Contents  string   // term reported for a contents search
+	// ContentsQuery is the intext: part of a contents search, see
+	// ContentsQuery.
+	ContentsQuery string
+	// Wildcard makes the plain search (no dork, extension, terms or
+	// contents) site:*.target, which leaves out the target itself.
+	Wildcard bool
+	// All searches Terms with allinurl: instead of inurl:, and moves
+	// ContentsQuery (built with AllQuery) to the end of the query, where
+	// an allin* operator must be.
+	All bool
+}
+
+// BuildQueries returns the queries for s, in Google syntax. Each query is
+// put together from its parts (scope, terms, exclusions) as plain text; the
+// provider escapes it once when building the request URL, so characters
+// like &, # and + in a dork reach the engine unchanged.
+func BuildQueries(s QuerySpec) []Request {
+	var out []Request
+	add := func(term string, parts ...string) {
+		out = append(out, Request{Term: term, Query: joinQuery(append(parts, s.Exclusions)...)})
+	}
+	// Google takes everything after allinurl: or allintext: as its words,
+	// so with All the term's part comes after the exclusions
+	addTerm := func(term, scope, part string) {
+		if s.All {
+			out = append(out, Request{Term: term, Query: joinQuery(scope, s.Exclusions, part)})
+		} else {
+			add(term, scope, part)
+		}
+	}
+	t := ASCIIHost(NormalizeHost(s.Target))
+	dork := strings.TrimSpace(s.Dork)
+	switch {
+	case dork != "":
+		if s.Subdomains {
+			add(s.Dork, "site:*."+t, dork, "-www."+t)
+			add(s.Dork, "site:*.*."+t, dork)
+			add(s.Dork, "site:*.*.*."+t, dork)
+			add(s.Dork, "site:*."+t, dork, excludeCommonHosts(t))
+		} else {
+			add(s.Dork, "site:"+t, dork)
+		}
+
+	case s.Extension != "":
+		ext := strings.TrimSpace(s.Extension)
+		scopes := []string{"site:" + t}
+		if s.Subdomains {
+			scopes = append(scopes, "site:*."+t, "site:*.*."+t, "site:*.*.*."+t)
+		}
+		for _, scope := range scopes {
+			add(ext, scope, "filetype:"+ext)
+			add(ext, scope, "ext:"+ext)
+		}
+
+	case len(s.Terms) > 0:
+		scopes := []string{"site:" + t}
+		if s.Subdomains {
+			scopes = []string{"site:*." + t, "site:*.*." + t, "site:*.*.*." + t}
+		}
+		for _, term := range s.Terms {
+			term = strings.TrimSpace(term)
+			if term == "" {
+				continue
+			}
+			part := quoted("inurl", term)
+			if s.All {
+				part = AllQuery("allinurl", term)
+			}
+			for _, scope := range scopes {
+				addTerm(term, scope, part)
+			}
+		}
+
+	case s.Contents != "":
+		if s.Subdomains {
+			for _, scope := range []string{"site:*." + t, "site:*.*." + t, "site:*.*.*." + t} {
+				addTerm(s.Contents, scope, s.ContentsQuery)
+			}
+		} else {
+			addTerm(s.Contents, "site:"+t, s.ContentsQuery)
+		}
+
+	case s.Wildcard:
+		add("", "site:*."+t)
+
+	default:
+		add("", "site:"+t)
+	}
+	return out
+}
+
+// joinQuery joins the non-empty parts of a query with single spaces.
+func joinQuery(parts ...string) string {
+	kept := parts[:0:0]
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// quoted renders op:"v". Quotes inside v would end the phrase early, so
+// they are dropped.
+func quoted(op, v string) string {
+	return op + `:"` + strings.ReplaceAll(v, `"`, "") + `"`
+}
+
+// commonHosts are the subdomains left out of a subdomain dork's last query,
+// which tend to crowd out the interesting ones.
+var commonHosts = []string{
+	"www", "techblog", "infohub", "blog", "store", "support", "help", "addons",
+	"forum", "community", "docs", "developer", "about", "resources", "cdn",
+	"career", "faq", "news", "jobs", "library", "id", "blogs", "faq", "trust",
+	"forums", "dl", "downloads",
+}
+
+func excludeCommonHosts(target string) string {
+	parts := make([]string, len(commonHosts))
+	for i, h := range commonHosts {
+		parts[i] = fmt.Sprintf("-%s.%s", h, target)
+	}
+	return strings.Join(parts, " ")
+}
+
+// SplitExclusions sorts exclusion entries into hosts and URL paths (those
+// starting with "/").
+func SplitExclusions(entries []string) (hosts, paths []string) {
+	for _, ex := range entries {
+		if ex = strings.TrimSpace(ex); ex == "" {
+			continue
+		}
+		if strings.HasPrefix(ex, "/") {
+			paths = append(paths, ex)
+		} else {
+			hosts = append(hosts, ex)
+		}
+	}
+	return hosts, paths
+}
+
+// ExclusionQuery builds the query suffix excluding hosts and paths:
+// -site:host for each host followed by -inurl:"/path" for each path, all
+// separated by spaces. A wildcard host (*.dev.example.com) becomes
+// -site:dev.example.com, which covers the whole subtree.
+func ExclusionQuery(hosts, paths []string) string {
+	parts := make([]string, 0, len(hosts)+len(paths))
+	for _, h := range hosts {
+		parts = append(parts, "-site:"+ASCIIHost(NormalizeHost(strings.TrimPrefix(h, "*."))))
+	}
+	for _, p := range paths {
+		parts = append(parts, "-"+quoted("inurl", p))
+	}
+	return strings.Join(parts, " ")
+}
+
+// WildcardHosts returns the base domains of the wildcard entries among
+// hosts (dev.example.com for *.dev.example.com), for Filter.ExcludeHosts.
+func WildcardHosts(hosts []string) []string {
+	var out []string
+	for _, h := range hosts {
+		if base, ok := strings.CutPrefix(h, "*."); ok && base != "" {
+			out = append(out, ASCIIHost(NormalizeHost(base)))
+		}
+	}
+	return out
+}
+
+// ContentsQuery ORs intext:"term" for each term.
+func ContentsQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = quoted("intext", t)
+	}
+	return strings.Join(parts, " OR ")
+}
+
+// AnyWordQuery ORs intext:"word" for each word of term, matching pages
+// that contain any of them in any order, as (intext:"a" OR intext:"b"). A
+// single word is just intext:"word".
+func AnyWordQuery(term string) string {
+	words := strings.Fields(strings.ReplaceAll(term, `"`, ""))
+	if len(words) == 1 {
+		return quoted("intext", words[0])
+	}
+	parts := make([]string, len(words))
+	for i, w := range words {
+		parts[i] = quoted("intext", w)
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+// AllQuery renders "op: word word..." for allinurl: and allintext:, which
+// match every word of term in any order. Quotes are dropped: the words are
+// matched one by one, not as a phrase.
+func AllQuery(op, term string) string {
+	return op + ": " + strings.Join(strings.Fields(strings.ReplaceAll(term, `"`, "")), " ")
+}
+
+// CheckAllTerm reports why term can't follow allinurl: or allintext:,
+// which don't combine with other operators: Google would take them as
+// words to match, or drop the rest of the query.
+func CheckAllTerm(term string) error {
+	for _, w := range strings.Fields(strings.ReplaceAll(term, `"`, "")) {
+		switch {
+		case strings.Contains(w, ":"):
+			return fmt.Errorf("%q is an operator", w)
+		case strings.HasPrefix(w, "-"):
+			return fmt.Errorf("%q excludes a word", w)
+		case w == "OR" || w == "AND" || w == "|":
+			return fmt.Errorf("%q combines searches", w)
+		}
+	}
+	return nil
+}
+
+// CleanTerm trims a dictionary term and strips surrounding quotes, since
+// each term is wrapped as inurl:"term".
+func CleanTerm(s string) string {
+	return strings.Trim(strings.TrimSpace(s), `"`)
+}
@@ -0,0 +1,108 @@
+package banshee
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// The last query of a -a dork excludes the common subdomains, each of them
+// under the target. Before the hosts were listed in commonHosts the query
+// was one Sprintf with a target argument missing, which sent
+// "-downloads.%!s(MISSING)" to Google.
+func TestSubdomainDorkExcludesCommonHosts(t *testing.T) {
+	reqs := BuildQueries(QuerySpec{Target: "example.com", Dork: "inurl:login", Subdomains: true})
+	if len(reqs) != 4 {
+		t.Fatalf("got %d queries, want 4", len(reqs))
+	}
+	q := reqs[3].Query
+	if strings.Contains(q, "%!") {
+		t.Fatalf("query has a formatting error: %s", q)
+	}
+	if !strings.HasPrefix(q, "site:*.example.com inurl:login ") {
+		t.Errorf("query = %q, want it to start with the scope and the dork", q)
+	}
+	for _, h := range commonHosts {
+		if !strings.Contains(q, " -"+h+".example.com") {
+			t.Errorf("query doesn't exclude %s.example.com: %s", h, q)
+		}
+	}
+	if !strings.HasSuffix(q, " -downloads.example.com") {
+		t.Errorf("query = %q, want it to end with -downloads.example.com", q)
+	}
+}
+
+func TestSplitExclusions(t *testing.T) {
+	hosts, paths := SplitExclusions([]string{" dev.example.com", "/blog/", "", "*.staging.example.com", "/press/ "})
+	if want := []string{"dev.example.com", "*.staging.example.com"}; !slices.Equal(hosts, want) {
+		t.Errorf("hosts = %q, want %q", hosts, want)
+	}
+	if want := []string{"/blog/", "/press/"}; !slices.Equal(paths, want) {
+		t.Errorf("paths = %q, want %q", paths, want)
+	}
+}
+
+func TestExclusionQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		hosts, paths []string
+		want         string
+	}{
+		{"hosts", []string{"dev.example.com", "Shop.Example.com."}, nil, "-site:dev.example.com -site:shop.example.com"},
+		{"wildcard host", []string{"*.staging.example.com"}, nil, "-site:staging.example.com"},
+		{"paths", nil, []string{"/blog/", "/careers/"}, `-inurl:"/blog/" -inurl:"/careers/"`},
+		{"quoted path", nil, []string{`/a"b/`}, `-inurl:"/ab/"`},
+		{"mixed", []string{"dev.example.com"}, []string{"/blog/"}, `-site:dev.example.com -inurl:"/blog/"`},
+		{"none", nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExclusionQuery(tt.hosts, tt.paths); got != tt.want {
+				t.Errorf("ExclusionQuery(%q, %q) = %q, want %q", tt.hosts, tt.paths, got, tt.want)
+			}
+		})
+	}
+}
+
+// Queries are plain text: the parts are joined with single spaces, the
+// target normalised, and characters that mean something in a URL left
+// alone for the provider to escape.
+func TestBuildQueries(t *testing.T) {
+	tests := []struct {
+		name string
+		spec QuerySpec
+		want []string
+	}{
+		{"dork with URL characters", QuerySpec{Target: "example.com", Dork: `inurl:redirect= intext:"a&b" #anchor c++`},
+			[]string{`site:example.com inurl:redirect= intext:"a&b" #anchor c++`}},
+		{"padded dork", QuerySpec{Target: "example.com", Dork: "  inurl:login  "},
+			[]string{"site:example.com inurl:login"}},
+		{"uppercase target, trailing dot", QuerySpec{Target: "Example.COM.", Dork: "inurl:login"},
+			[]string{"site:example.com inurl:login"}},
+		{"IDN target", QuerySpec{Target: "bücher.example", Dork: "inurl:login"},
+			[]string{"site:xn--bcher-kva.example inurl:login"}},
+		{"exclusions", QuerySpec{Target: "example.com", Dork: "inurl:login", Exclusions: `-site:dev.example.com -inurl:"/blog/"`},
+			[]string{`site:example.com inurl:login -site:dev.example.com -inurl:"/blog/"`}},
+		{"extension", QuerySpec{Target: "example.com", Extension: " pdf "},
+			[]string{"site:example.com filetype:pdf", "site:example.com ext:pdf"}},
+		{"terms with quotes", QuerySpec{Target: "example.com", Terms: []string{"admin", `a"b`}},
+			[]string{`site:example.com inurl:"admin"`, `site:example.com inurl:"ab"`}},
+		{"allinurl after the exclusions", QuerySpec{Target: "example.com", Terms: []string{"admin"}, All: true, Exclusions: "-site:dev.example.com"},
+			[]string{"site:example.com -site:dev.example.com allinurl: admin"}},
+		{"contents", QuerySpec{Target: "example.com", Contents: "secret", ContentsQuery: ContentsQuery([]string{"secret", "top & secret"})},
+			[]string{`site:example.com intext:"secret" OR intext:"top & secret"`}},
+		{"site listing", QuerySpec{Target: "example.com"}, []string{"site:example.com"}},
+		{"wildcard listing", QuerySpec{Target: "example.com", Wildcard: true}, []string{"site:*.example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range BuildQueries(tt.spec) {
+				got = append(got, r.Query)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("BuildQueries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package banshee
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SerpAPI runs the same Google queries through serpapi.com for users who
+// already pay for it.
+
+const serpAPIURL = "https://serpapi.com/search.json"
+
+type serpResponse struct {
+	OrganicResults []struct {
+		Link string `json:"link"`
+	} `json:"organic_results"`
+	Error string `json:"error"`
+}
+
+// serpBaseURL is the request URL for one page, up to the query value.
+// SerpAPI takes a 0-based result offset where CSE takes a 1-based index.
+func serpBaseURL(key string, startIdx int) string {
+	return fmt.Sprintf("%s?engine=google&num=10&api_key=%s&start=%d&q=", serpAPIURL, url.QueryEscape(key), startIdx-1)
+}
+
+// decodeSerp extracts the organic result links. An empty result set is
+// reported by SerpAPI as an error message, which is not treated as one.
+func decodeSerp(body []byte) (links []string, apiErr *apiError, err error) {
+	var sr serpResponse
+	if err := json.Unmarshal(body, &sr); err != nil {
+		return nil, nil, fmt.Errorf("decode error: %w", err)
+	}
+	if sr.Error != "" && !strings.Contains(strings.ToLower(sr.Error), "hasn't returned any results") {
+		e := &apiError{msg: sr.Error}
+		if serpQuotaError(sr.Error) {
+			e.kind = errQuota
+		}
+		return nil, e, nil
+	}
+	for _, r := range sr.OrganicResults {
+		links = append(links, r.Link)
+	}
+	return links, nil, nil
+}
+
+// serpQuotaError reports whether msg means the account has no searches left.
+func serpQuotaError(msg string) bool {
+	m := strings.ToLower(msg)
+	return strings.Contains(m, "run out of searches") || strings.Contains(m, "ran out of searches")
+}
@@ -0,0 +1,92 @@
+package banshee
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Yandex uses the Yandex Search XML API, which indexes a lot that Google
+// doesn't, notably for CIS-region targets. Keys are "user:key" pairs.
+
+const yandexAPIURL = "https://yandex.com/search/xml"
+
+type yandexResponse struct {
+	Response struct {
+		Error *struct {
+			Code int    `xml:"code,attr"`
+			Msg  string `xml:",chardata"`
+		} `xml:"error"`
+		Groups []struct {
+			Docs []struct {
+				URL string `xml:"url"`
+			} `xml:"doc"`
+		} `xml:"results>grouping>group"`
+	} `xml:"response"`
+}
+
+// Yandex error codes: 15 is "no results", 32 and 55 are the daily and
+// hourly request limits.
+const (
+	yandexNoResults   = 15
+	yandexDailyLimit  = 32
+	yandexHourlyLimit = 55
+)
+
+// yandexBaseURL is the request URL for one page, up to the query value.
+// Yandex pages are 0-based; results are flattened to one document per
+// group so a page holds 10 links like the other engines.
+func yandexBaseURL(cred string, startIdx int) string {
+	user, key, _ := strings.Cut(cred, ":")
+	v := url.Values{}
+	v.Set("user", user)
+	v.Set("key", key)
+	v.Set("l10n", "en")
+	v.Set("filter", "none")
+	v.Set("groupby", "attr=.mode=flat.groups-on-page=10.docs-in-group=1")
+	v.Set("page", fmt.Sprint((startIdx-1)/10))
+	return yandexAPIURL + "?" + v.Encode() + "&query="
+}
+
+func decodeYandex(body []byte) (links []string, apiErr *apiError, err error) {
+	var yr yandexResponse
+	if err := xml.Unmarshal(body, &yr); err != nil {
+		return nil, nil, fmt.Errorf("decode error: %w", err)
+	}
+	if e := yr.Response.Error; e != nil {
+		if e.Code == yandexNoResults {
+			return nil, nil, nil
+		}
+		ae := &apiError{msg: fmt.Sprintf("yandex error %d: %s", e.Code, strings.TrimSpace(e.Msg))}
+		if yandexQuotaError(ae.msg) {
+			ae.kind = errQuota
+		}
+		return nil, ae, nil
+	}
+	for _, g := range yr.Response.Groups {
+		for _, d := range g.Docs {
+			links = append(links, d.URL)
+		}
+	}
+	return links, nil, nil
+}
+
+func yandexQuotaError(msg string) bool {
+	return strings.HasPrefix(msg, fmt.Sprintf("yandex error %d:", yandexDailyLimit)) ||
+		strings.HasPrefix(msg, fmt.Sprintf("yandex error %d:", yandexHourlyLimit))
+}
+
+var (
+	yandexFiletypeRe = regexp.MustCompile(`\b(?:filetype|ext):`)
+	yandexWildSiteRe = regexp.MustCompile(`\bsite:(?:\*\.)+`)
+)
+
+// adaptYandexQuery rewrites Google operators into their Yandex forms:
+// filetype:/ext: become mime:, and site: wildcards are dropped because
+// Yandex's site: already covers subdomains.
+func adaptYandexQuery(q string) string {
+	q = yandexFiletypeRe.ReplaceAllString(q, "mime:")
+	return yandexWildSiteRe.ReplaceAllString(q, "site:")
+}